@@ -2,22 +2,131 @@ package kaval
 
 import (
 	"fmt"
+	"iter"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 var (
 	ErrOddNumberOfPairs         = fmt.Errorf("odd number of pairs")
 	ErrOrderedFieldAfterLabeled = fmt.Errorf("ordered field after labeled field")
 	ErrInvalidFieldName         = fmt.Errorf("invalid field name")
+	ErrDuplicateFieldName       = fmt.Errorf("duplicate field name")
+	ErrOrderedFieldInMap        = fmt.Errorf("ordered field in map-only builder")
+	ErrLabeledFieldInList       = fmt.Errorf("labeled field in list-only builder")
+	ErrCompositeKey             = fmt.Errorf("list or map cannot be a map key")
+	ErrEmptyRow                 = fmt.Errorf("empty row in list of maps")
 )
 
 // NeedsQuoting returns true if the given string needs quotes.
+//
+// A string can be written unquoted only if the lexer reads it back as a
+// single identifier or number token spanning the whole string. Keywords,
+// separators and any character the lexer does not accept unquoted therefore
+// all require quoting.
 func NeedsQuoting(s string) bool {
-	return s == "" ||
-		strings.ContainsAny(s, ` ,;:=\`) ||
-		s == "true" || s == "false" || s == "nil"
+	return needsQuoting(s, LexDefaults())
 }
 
+// needsQuoting reports whether s needs quotes when lexed with the given options.
+func needsQuoting(s string, opt LexOptions) bool {
+	for tok := range Lex(s, opt) {
+		switch tok.Typ {
+		case TokenIdentifier, TokenNumber:
+			return tok.Val != s
+		default:
+			return true
+		}
+	}
+	return true
+}
+
+// TokensToString renders tokens as plainfields text that lexes back to the
+// same token types and values.
+//
+// Whitespace between tokens is not kept, except for a single space between
+// adjacent tokens that would otherwise lex as one, e.g. two identifiers.
+// EOF tokens are skipped. An error is returned for tokens whose value does
+// not lex back to a token of the same type, including error tokens.
+func TokensToString(tokens []Token) (string, error) {
+	var sb strings.Builder
+
+	var prev TokenType = TokenEOF
+	for _, tok := range tokens {
+		if tok.Typ == TokenEOF {
+			continue
+		}
+		if !lexesAs(tok) {
+			return "", fmt.Errorf("%s token %q cannot be rendered", tok.Typ, tok.Val)
+		}
+
+		if isWordToken(prev) && isWordToken(tok.Typ) {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(tok.Val)
+		prev = tok.Typ
+	}
+
+	return sb.String(), nil
+}
+
+// lexesAs reports whether the value of tok lexes as a single token of the
+// same type.
+func lexesAs(tok Token) bool {
+	for got := range Lex(tok.Val) {
+		return got.Typ == tok.Typ && got.Val == tok.Val
+	}
+	return false
+}
+
+// isWordToken reports whether tokens of the given type merge with adjacent
+// tokens of such a type when written without whitespace.
+func isWordToken(typ TokenType) bool {
+	switch typ {
+	case TokenIdentifier, TokenNumber, TokenTrue, TokenFalse, TokenNil:
+		return true
+	default:
+		return false
+	}
+}
+
+// KV is a key-value pair of a dict field.
+type KV struct {
+	Key   string
+	Value any
+}
+
+// BuilderMode restricts the fields a Builder accepts.
+type BuilderMode int
+
+const (
+	// BuilderModeAny accepts ordered and labeled fields.
+	BuilderModeAny BuilderMode = iota
+
+	// BuilderModeMap only accepts labeled fields, adding an ordered field
+	// fails with ErrOrderedFieldInMap.
+	BuilderModeMap
+
+	// BuilderModeList only accepts ordered fields, adding a labeled field
+	// fails with ErrLabeledFieldInList.
+	BuilderModeList
+)
+
+// BooleanStyle selects how Builder.Boolean writes boolean fields.
+type BooleanStyle int
+
+const (
+	// BooleanStylePrefix writes ^name for true and !name for false.
+	BooleanStylePrefix BooleanStyle = iota
+
+	// BooleanStyleExplicit writes name=true and name=false.
+	BooleanStyleExplicit
+)
+
 // BuilderOptions controls Builder formatting behavior.
 type BuilderOptions struct {
 	// AlwaysQuoteStrings forces all strings to be quoted.
@@ -34,20 +143,183 @@ type BuilderOptions struct {
 
 	// SpaceAroundFieldAssignment adds a space around the field assignment `=`.
 	SpaceAroundFieldAssignment bool
+
+	// PromoteOrderedToKeyed labels ordered values added after a labeled field
+	// instead of failing with ErrOrderedFieldAfterLabeled. The promoted values
+	// are keyed by their count starting at 0, i.e. the first one is written
+	// as 0=value, the second as 1=value and so on.
+	//
+	// The output is write-only: numeric field names do not lex as
+	// identifiers, so parsing it fails with "ordered value not allowed here"
+	// at the first promoted value.
+	PromoteOrderedToKeyed bool
+
+	// RejectDuplicateKeys fails with ErrDuplicateFieldName when a field name
+	// is used more than once, including boolean fields.
+	RejectDuplicateKeys bool
+
+	// Mode restricts the document to labeled or ordered fields only.
+	Mode BuilderMode
+
+	// BooleanStyle selects how Boolean writes boolean fields. Enable and
+	// Disable always use the prefix notation.
+	BooleanStyle BooleanStyle
+
+	// MinimalQuoting writes parsed strings without quotes if they read back
+	// as an identifier of the same text, e.g. "john" as john. Strings that
+	// would lex differently, such as "true", "42" or "a;b", stay quoted.
+	MinimalQuoting bool
+
+	// MaxLineLength starts a new line after a field once the current line
+	// is longer than the given number of characters. Lines are only broken
+	// between fields, so a single long field may exceed it. Zero means no
+	// limit.
+	MaxLineLength int
+
+	// Lex holds the options the output is read back with. Its separators
+	// replace the default separators, and values are quoted as needed for
+	// them to be read back as single values.
+	Lex LexOptions
 }
 
 // formatValue returns a string representation suitable for plainfields.
-func (opt BuilderOptions) formatValue(v any) string {
+func (opt BuilderOptions) formatValue(v any) (string, error) {
 	switch val := v.(type) {
 	case string:
-		if opt.AlwaysQuoteStrings || NeedsQuoting(val) {
-			return fmt.Sprintf("%q", val)
+		if opt.AlwaysQuoteStrings || needsQuoting(val, opt.Lex) {
+			return fmt.Sprintf("%q", val), nil
 		}
-		return val
+		return val, nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case float64:
+		return opt.formatFloat(val, 64), nil
+	case float32:
+		return opt.formatFloat(float64(val), 32), nil
 	case nil:
-		return "nil"
+		return "nil", nil
+	case Value:
+		if opt.MinimalQuoting {
+			val = unquoteValue(val, opt.Lex)
+		}
+		return opt.formatParsed(val)
+	default:
+		// Quote anything whose text would not read back as a single value,
+		// e.g. a fmt.Stringer that formats to a keyword.
+		s := fmt.Sprint(v)
+		if needsQuoting(s, opt.Lex) {
+			return fmt.Sprintf("%q", s), nil
+		}
+		return s, nil
+	}
+}
+
+// formatParsed returns the text of a parsed value. Scalars are already in
+// their canonical encoding, lists and maps are joined with the configured
+// separators.
+func (opt BuilderOptions) formatParsed(v Value) (string, error) {
+	switch val := v.(type) {
+	case ListValue:
+		items := make([]string, len(val.items))
+		for i, item := range val.items {
+			s, err := opt.formatItem(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return strings.Join(items, opt.listSeparator()), nil
+	case MapValue:
+		entries := make([]string, len(val.keys))
+		for i, key := range val.keys {
+			entry, err := opt.formatEntry(key, val.values[i])
+			if err != nil {
+				return "", err
+			}
+			entries[i] = entry
+		}
+		return strings.Join(entries, opt.listSeparator()), nil
 	default:
-		return fmt.Sprint(v)
+		return val.Raw(), nil
+	}
+}
+
+// formatItem returns the text of a list item or map value. Lists and maps
+// are enclosed in groups.
+func (opt BuilderOptions) formatItem(v any) (string, error) {
+	s, err := opt.formatValue(v)
+	if err != nil {
+		return "", err
+	}
+	if nested, ok := v.(Value); ok {
+		s = group(nested, s)
+	}
+	return s, nil
+}
+
+// formatKey returns the text of a map key, which cannot be a list or map.
+func (opt BuilderOptions) formatKey(v any) (string, error) {
+	switch v.(type) {
+	case ListValue, MapValue:
+		return "", ErrCompositeKey
+	default:
+		return opt.formatValue(v)
+	}
+}
+
+// formatEntry returns the text of a map entry.
+func (opt BuilderOptions) formatEntry(key, value any) (string, error) {
+	k, err := opt.formatKey(key)
+	if err != nil {
+		return "", err
+	}
+	v, err := opt.formatItem(value)
+	if err != nil {
+		return "", err
+	}
+	return k + opt.pairSeparator() + v, nil
+}
+
+// formatFloat returns the shortest representation of f that reads back as
+// the same number. Infinities such as +Inf do not lex as a single value and
+// NaN would read back as an identifier, so both are quoted.
+func (opt BuilderOptions) formatFloat(f float64, bitSize int) string {
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if math.IsNaN(f) || needsQuoting(s, opt.Lex) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// unquoteValue returns v with the strings that read back as identifiers of
+// the same text replaced by these identifiers.
+func unquoteValue(v Value, opt LexOptions) Value {
+	switch val := v.(type) {
+	case StringValue:
+		s, err := val.ToString()
+		if err != nil {
+			return v
+		}
+		for tok := range Lex(s, opt) {
+			if tok.Typ == TokenIdentifier && tok.Val == s {
+				return IdentifierValue{raw: s}
+			}
+		}
+		return v
+	case ListValue:
+		items := make([]Value, len(val.items))
+		for i, item := range val.items {
+			items[i] = unquoteValue(item, opt)
+		}
+		return ListValue{items: items}
+	case MapValue:
+		var dict MapValue
+		for i, key := range val.keys {
+			dict.set(unquoteValue(key, opt), unquoteValue(val.values[i], opt))
+		}
+		return dict
+	default:
+		return v
 	}
 }
 
@@ -66,7 +338,10 @@ type Builder struct {
 	fields     []string
 	hasLabeled bool   // Track if we've seen any labeled fields.
 	nextLabel  string // Track the next label to be used.
-	err        error  // Track the last error that occurred.
+	promoted   int    // Count the ordered values promoted to labeled fields.
+
+	labels map[string]struct{} // Track the field names used so far.
+	err    error               // Track the last error that occurred.
 }
 
 // setError sets the last error that occurred.
@@ -90,7 +365,20 @@ func (b *Builder) add(value string) *Builder {
 		return b
 	}
 
+	if b.nextLabel == "" && b.hasLabeled && b.options.PromoteOrderedToKeyed {
+		b.nextLabel = strconv.Itoa(b.promoted)
+		b.promoted++
+	}
+
+	if b.nextLabel == "" && b.options.Mode == BuilderModeMap {
+		return b.setError(ErrOrderedFieldInMap)
+	}
+
 	if b.nextLabel != "" {
+		if !b.claimLabel(b.nextLabel) {
+			return b
+		}
+
 		separator := "="
 		if b.options.SpaceAroundFieldAssignment {
 			separator = " = "
@@ -107,6 +395,31 @@ func (b *Builder) add(value string) *Builder {
 	return b.addRaw(value)
 }
 
+// claimLabel records the use of a field name. It reports false and sets an
+// error if labeled fields are not allowed, or if the name was used before
+// and duplicates are rejected.
+func (b *Builder) claimLabel(name string) bool {
+	if b.options.Mode == BuilderModeList {
+		b.setError(fmt.Errorf("%q: %w", name, ErrLabeledFieldInList))
+		return false
+	}
+
+	if !b.options.RejectDuplicateKeys {
+		return true
+	}
+
+	if _, ok := b.labels[name]; ok {
+		b.setError(fmt.Errorf("%q: %w", name, ErrDuplicateFieldName))
+		return false
+	}
+
+	if b.labels == nil {
+		b.labels = make(map[string]struct{})
+	}
+	b.labels[name] = struct{}{}
+	return true
+}
+
 // Err returns the last error that occurred if any.
 func (b *Builder) Err() error {
 	return b.err
@@ -114,63 +427,72 @@ func (b *Builder) Err() error {
 
 // Value adds an ordered value to the builder.
 func (b *Builder) Value(value any) *Builder {
-	return b.add(b.options.formatValue(value))
+	s, err := b.options.formatValue(value)
+	if err != nil {
+		return b.setError(err)
+	}
+	return b.add(s)
 }
 
-// List adds a [name=]value1;value2;... field.
+// List adds a [name=]value1;value2;... field. Lists and maps as items are
+// enclosed in groups, e.g. (a:1);(b:2).
 func (b *Builder) List(values ...any) *Builder {
 	items := make([]string, len(values))
 	for i, v := range values {
-		items[i] = b.options.formatValue(v)
-	}
-
-	separator := ";"
-	if b.options.SpaceAfterListSeparator {
-		separator = "; "
+		s, err := b.options.formatItem(v)
+		if err != nil {
+			return b.setError(err)
+		}
+		items[i] = s
 	}
 
-	return b.add(strings.Join(items, separator))
+	return b.add(strings.Join(items, b.options.listSeparator()))
 }
 
-// Dict adds a [name=]key1:value1;key2:value2;... field.
+// Dict adds a [name=]key1:value1;key2:value2;... field. Lists and maps as
+// values are enclosed in groups, e.g. key:(a;b), and fail with
+// ErrCompositeKey as keys.
 func (b *Builder) Dict(pairs ...any) *Builder {
 	if len(pairs)%2 != 0 {
 		return b.setError(ErrOddNumberOfPairs)
 	}
 
-	colon := ":"
-	if b.options.SpaceAfterPairsSeparator {
-		colon = ": "
-	}
-
 	items := make([]string, 0, len(pairs)/2)
 	for i := 0; i < len(pairs); i += 2 {
-		k := b.options.formatValue(pairs[i])
-		v := b.options.formatValue(pairs[i+1])
-		items = append(items, k+colon+v)
+		entry, err := b.options.formatEntry(pairs[i], pairs[i+1])
+		if err != nil {
+			return b.setError(err)
+		}
+		items = append(items, entry)
 	}
 
-	separator := ";"
-	if b.options.SpaceAfterListSeparator {
-		separator = "; "
-	}
-	return b.add(strings.Join(items, separator))
+	return b.add(strings.Join(items, b.options.listSeparator()))
 }
 
 // Enable adds a boolean field with ^ prefix.
 func (b *Builder) Enable(name string) *Builder {
+	if !b.claimLabel(name) {
+		return b
+	}
 	b.hasLabeled = true
 	return b.addRaw("^" + name)
 }
 
 // Disable adds a boolean field with ! prefix.
 func (b *Builder) Disable(name string) *Builder {
+	if !b.claimLabel(name) {
+		return b
+	}
 	b.hasLabeled = true
 	return b.addRaw("!" + name)
 }
 
-// Boolean adds a boolean field with ^ or ! prefix.
+// Boolean adds a boolean field with ^ or ! prefix, or as name=value with
+// BooleanStyleExplicit.
 func (b *Builder) Boolean(name string, value bool) *Builder {
+	if b.options.BooleanStyle == BooleanStyleExplicit {
+		return b.Labeled(name, value)
+	}
 	if value {
 		return b.Enable(name)
 	}
@@ -179,7 +501,7 @@ func (b *Builder) Boolean(name string, value bool) *Builder {
 
 // Label sets the name of the field for the next value.
 func (b *Builder) Label(name string) *Builder {
-	if NeedsQuoting(name) {
+	if needsQuoting(name, b.options.Lex) {
 		return b.setError(fmt.Errorf("%q: %w", name, ErrInvalidFieldName))
 	}
 	b.nextLabel = name
@@ -201,17 +523,222 @@ func (b *Builder) LabeledDict(name string, pairs ...any) *Builder {
 	return b.Label(name).Dict(pairs...)
 }
 
+// LabeledBytes adds a name="base64" field holding b encoded as base64.
+func (b *Builder) LabeledBytes(name string, data []byte) *Builder {
+	return b.Labeled(name, NewBytes(data))
+}
+
+// LabeledListOfDicts adds a name=(key1:value1;...);(...)[;...] field with a
+// group for each row, written with sorted keys. An empty row cannot be
+// written as a group and fails with ErrEmptyRow.
+func (b *Builder) LabeledListOfDicts(name string, rows []map[string]any) *Builder {
+	b.Label(name)
+
+	items := make([]string, len(rows))
+	for i, row := range rows {
+		if len(row) == 0 {
+			return b.setError(fmt.Errorf("%q: %w", name, ErrEmptyRow))
+		}
+
+		entries := make([]string, 0, len(row))
+		for _, key := range slices.Sorted(maps.Keys(row)) {
+			entry, err := b.options.formatEntry(key, row[key])
+			if err != nil {
+				return b.setError(err)
+			}
+			entries = append(entries, entry)
+		}
+		items[i] = "(" + strings.Join(entries, b.options.listSeparator()) + ")"
+	}
+
+	return b.add(strings.Join(items, b.options.listSeparator()))
+}
+
+// LabeledPairs adds a [name=]key1:value1;key2:value2[;...] field from kvs.
+func (b *Builder) LabeledPairs(name string, kvs ...KV) *Builder {
+	pairs := make([]any, 0, len(kvs)*2)
+	for _, kv := range kvs {
+		pairs = append(pairs, kv.Key, kv.Value)
+	}
+	return b.LabeledDict(name, pairs...)
+}
+
+// LabeledDictOfValues adds a name=key1:value1;key2:value2[;...] field from
+// parsed values, written in their canonical encoding with sorted keys.
+// Nested lists and maps are enclosed in groups.
+func (b *Builder) LabeledDictOfValues(name string, m map[string]Value) *Builder {
+	var dict MapValue
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		v := m[key]
+		if v == nil {
+			v = NilValue{}
+		}
+		dict.set(textValue(key), v)
+	}
+	return b.Labeled(name, dict)
+}
+
+// AppendLabeledIfSet adds a name=value field from a parsed Value, skipping it
+// if the value is nil or zero as reported by IsZero.
+func (b *Builder) AppendLabeledIfSet(name string, v Value) *Builder {
+	if IsZero(v) {
+		return b
+	}
+	return b.Labeled(name, v)
+}
+
+// AppendParsed parses the input and appends its fields to the builder.
+//
+// Ordered values and labeled fields are appended in their original order,
+// each encoded by its raw form. A parse error is returned without changing
+// the builder, otherwise the builder's error is returned as by Err.
+func (b *Builder) AppendParsed(input string, opts ...ParseOptions) error {
+	doc, err := decodeDocument(Parse(input, opts...))
+	if err != nil {
+		return err
+	}
+	return b.appendDocument(doc).Err()
+}
+
+// Encode encodes parser events back into text, e.g. after transforming the
+// events of a parsed input.
+//
+// Values are encoded by their raw form, so a zero value is encoded as an
+// empty assignment and a nil value as nil.
+func Encode(events iter.Seq[ParserEvent], opts ...BuilderOptions) (string, error) {
+	doc, err := decodeDocument(events)
+	if err != nil {
+		return "", err
+	}
+
+	b := NewBuilder(opts...).appendDocument(doc)
+	return b.Build()
+}
+
+// Format parses the input and writes it back with the given options, e.g.
+// to normalize spacing and quoting. Comments are not kept.
+func Format(input string, opts ...BuilderOptions) (string, error) {
+	opt := BuilderDefaults()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	parseOpt := ParseDefaults()
+	parseOpt.Lex = opt.Lex
+	return Encode(Parse(input, parseOpt), opt)
+}
+
+// DetectQuoteStyle returns the quote character used by most strings of the
+// input, either a double or a single quote. Ties and inputs without strings
+// favor double quotes.
+func DetectQuoteStyle(input string, opts ...LexOptions) (byte, error) {
+	var double, single int
+	for tok := range Lex(input, opts...) {
+		switch tok.Typ {
+		case TokenError:
+			return 0, fmt.Errorf("error at %s: %s", tok.Pos, tok.Val)
+		case TokenString:
+			if tok.Val[0] == '\'' {
+				single++
+			} else {
+				double++
+			}
+		}
+	}
+
+	if single > double {
+		return '\'', nil
+	}
+	return '"', nil
+}
+
+// appendDocument appends the fields of a decoded document.
+func (b *Builder) appendDocument(doc document) *Builder {
+	for _, v := range doc.ordered.items {
+		b.Value(v)
+	}
+	for i, key := range doc.labeled.keys {
+		b.Labeled(textOf(key), doc.labeled.values[i])
+	}
+	return b
+}
+
+// fieldSeparator returns the separator placed between fields.
+func (b *Builder) fieldSeparator() string {
+	separator := b.options.Lex.separator(TokenFieldSeparator)
+	if b.options.SpaceAfterFieldSeparator {
+		separator += " "
+	}
+	return separator
+}
+
+// listSeparator returns the separator placed between list items and map entries.
+func (opt BuilderOptions) listSeparator() string {
+	separator := opt.Lex.separator(TokenListSeparator)
+	if opt.SpaceAfterListSeparator {
+		separator += " "
+	}
+	return separator
+}
+
+// pairSeparator returns the separator placed between a map key and value.
+func (opt BuilderOptions) pairSeparator() string {
+	separator := opt.Lex.separator(TokenPairSeparator)
+	if opt.SpaceAfterPairsSeparator {
+		separator += " "
+	}
+	return separator
+}
+
 // String returns the built plainfields string
 func (b *Builder) String() string {
 	if b.err != nil {
 		return ""
 	}
 
-	separator := ","
-	if b.options.SpaceAfterFieldSeparator {
-		separator = ", "
+	var sb strings.Builder
+	b.writeFields(func(s string) { sb.WriteString(s) })
+	return sb.String()
+}
+
+// Build returns the built plainfields string and the last error that
+// occurred, in which case the string is empty.
+func (b *Builder) Build() (string, error) {
+	return b.String(), b.err
+}
+
+// Size returns the length in bytes of the string returned by String.
+func (b *Builder) Size() int {
+	if b.err != nil || len(b.fields) == 0 {
+		return 0
+	}
+
+	var n int
+	b.writeFields(func(s string) { n += len(s) })
+	return n
+}
+
+// writeFields writes the fields joined by the field separator. With
+// MaxLineLength set, the separator is followed by a newline instead of a
+// space once the line is longer than the limit.
+func (b *Builder) writeFields(write func(string)) {
+	separator := b.fieldSeparator()
+	wrapped := strings.TrimRight(separator, " ") + "\n"
+
+	var line int // Length of the current line in runes.
+	for i, field := range b.fields {
+		if i > 0 {
+			if b.options.MaxLineLength > 0 && line > b.options.MaxLineLength {
+				write(wrapped)
+				line = 0
+			} else {
+				write(separator)
+				line += utf8.RuneCountInString(separator)
+			}
+		}
+		write(field)
+		line += utf8.RuneCountInString(field)
 	}
-	return strings.Join(b.fields, separator)
 }
 
 // NewBuilder creates a new plainfields builder.
@@ -1,9 +1,19 @@
 package kaval
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
 	"testing"
 )
 
+// testStringer is a fmt.Stringer returning its own text.
+type testStringer string
+
+func (s testStringer) String() string { return string(s) }
+
 func TestBuilder(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -51,6 +61,26 @@ func TestBuilder(t *testing.T) {
 			},
 			wanted: `simple=abc,spaces="hello world",special="a:b;c,d",empty="",keyword="true"`,
 		},
+		{
+			name: "quoting of characters rejected by the lexer",
+			builder: func(b *Builder) *Builder {
+				return b.Labeled("caret", "^on").
+					Labeled("bang", "a!b").
+					Labeled("hash", "#1")
+			},
+			wanted: `caret="^on",bang="a!b",hash="#1"`,
+		},
+		{
+			name: "stringers formatting to keywords",
+			builder: func(b *Builder) *Builder {
+				return b.Labeled("a", testStringer("nil")).
+					Labeled("b", testStringer("true")).
+					Labeled("c", testStringer("plain")).
+					Labeled("d", testStringer("two words")).
+					Labeled("e", false)
+			},
+			wanted: `a="nil",b="true",c=plain,d="two words",e=false`,
+		},
 		{
 			name: "empty list and pairs",
 			builder: func(b *Builder) *Builder {
@@ -66,6 +96,24 @@ func TestBuilder(t *testing.T) {
 			},
 			wanted: "mixed=string;123;true;nil;45.67",
 		},
+		{
+			name: "parsed values",
+			builder: func(b *Builder) *Builder {
+				return b.LabeledList("list", NumberValue{raw: "0x2A"}, StringValue{raw: `"a b"`}, IdentifierValue{raw: "c"}).
+					LabeledDict("dict", IdentifierValue{raw: "k"}, BooleanValue{raw: "true"}, "n", NilValue{}).
+					Labeled("nested", ListValue{[]Value{NumberValue{raw: "1"}, NumberValue{raw: "2"}}})
+			},
+			wanted: `list=0x2A;"a b";c,dict=k:true;n:nil,nested=1;2`,
+		},
+		{
+			name: "parsed composites in dicts are grouped",
+			builder: func(b *Builder) *Builder {
+				return b.LabeledDict("d",
+					"l", ListValue{[]Value{IdentifierValue{"a"}, IdentifierValue{"b"}}},
+					"m", MapValue{[]Value{IdentifierValue{"k"}}, []Value{IdentifierValue{"v"}}})
+			},
+			wanted: "d=l:(a;b);m:(k:v)",
+		},
 		{
 			name: "ordered values",
 			builder: func(b *Builder) *Builder {
@@ -151,6 +199,148 @@ func TestBuilder(t *testing.T) {
 			wanted: "^feature, name = john, tags = dev; prod, settings = theme: dark; fontSize: 14",
 		},
 
+		{
+			name: "multi-rune separators",
+			builder: func(b *Builder) *Builder {
+				return b.Labeled("a", 1).
+					LabeledList("tags", "dev", "a:b").
+					LabeledDict("m", "k", "v", "n", 2)
+			},
+			options: &BuilderOptions{
+				SpaceAfterFieldSeparator: true,
+				SpaceAfterPairsSeparator: true,
+				Lex:                      LexOptions{FieldSeparator: ",,", PairSeparator: "::"},
+			},
+			wanted: `a=1,, tags=dev;"a:b",, m=k:: v;n:: 2`,
+		},
+		{
+			name: "case-insensitive keywords are quoted",
+			builder: func(b *Builder) *Builder {
+				return b.Labeled("a", "TRUE").Labeled("b", "Yes")
+			},
+			options: &BuilderOptions{
+				Lex: LexOptions{CaseInsensitiveKeywords: true},
+			},
+			wanted: `a="TRUE",b=Yes`,
+		},
+		{
+			name: "promote ordered values after labeled field",
+			builder: func(b *Builder) *Builder {
+				return b.Value("x").
+					Labeled("a", 1).
+					Value(2).
+					List("dev", "prod").
+					Enable("on").
+					Labeled("b", 3)
+			},
+			options: &BuilderOptions{
+				PromoteOrderedToKeyed: true,
+			},
+			wanted: "x,a=1,0=2,1=dev;prod,^on,b=3",
+		},
+		{
+			name: "duplicate keys are allowed by default",
+			builder: func(b *Builder) *Builder {
+				return b.Labeled("a", 1).Labeled("a", 2)
+			},
+			wanted: "a=1,a=2",
+		},
+		{
+			name: "reject duplicate keys without duplicates",
+			builder: func(b *Builder) *Builder {
+				return b.Value("x").Enable("on").Labeled("a", 1).LabeledList("b", 2, 3)
+			},
+			options: &BuilderOptions{
+				RejectDuplicateKeys: true,
+			},
+			wanted: "x,^on,a=1,b=2;3",
+		},
+		{
+			name: "error: duplicate key",
+			builder: func(b *Builder) *Builder {
+				return b.Labeled("a", 1).Labeled("a", 2)
+			},
+			options: &BuilderOptions{
+				RejectDuplicateKeys: true,
+			},
+			wanted:  "",
+			wantErr: `"a": duplicate field name`,
+		},
+		{
+			name: "error: duplicate boolean key",
+			builder: func(b *Builder) *Builder {
+				return b.Labeled("on", 1).Disable("on")
+			},
+			options: &BuilderOptions{
+				RejectDuplicateKeys: true,
+			},
+			wanted:  "",
+			wantErr: `"on": duplicate field name`,
+		},
+		{
+			name: "map mode",
+			builder: func(b *Builder) *Builder {
+				return b.Labeled("a", 1).Enable("on").LabeledList("b", 2, 3)
+			},
+			options: &BuilderOptions{
+				Mode: BuilderModeMap,
+			},
+			wanted: "a=1,^on,b=2;3",
+		},
+		{
+			name: "error: ordered value in map mode",
+			builder: func(b *Builder) *Builder {
+				return b.Labeled("a", 1).Value("x")
+			},
+			options: &BuilderOptions{
+				Mode: BuilderModeMap,
+			},
+			wanted:  "",
+			wantErr: "ordered field in map-only builder",
+		},
+		{
+			name: "error: ordered list in map mode",
+			builder: func(b *Builder) *Builder {
+				return b.List(1, 2)
+			},
+			options: &BuilderOptions{
+				Mode: BuilderModeMap,
+			},
+			wanted:  "",
+			wantErr: "ordered field in map-only builder",
+		},
+		{
+			name: "list mode",
+			builder: func(b *Builder) *Builder {
+				return b.Value("x").List(1, 2).Dict("k", "v")
+			},
+			options: &BuilderOptions{
+				Mode: BuilderModeList,
+			},
+			wanted: "x,1;2,k:v",
+		},
+		{
+			name: "error: labeled value in list mode",
+			builder: func(b *Builder) *Builder {
+				return b.Value("x").Labeled("a", 1)
+			},
+			options: &BuilderOptions{
+				Mode: BuilderModeList,
+			},
+			wanted:  "",
+			wantErr: `"a": labeled field in list-only builder`,
+		},
+		{
+			name: "error: boolean field in list mode",
+			builder: func(b *Builder) *Builder {
+				return b.Enable("on")
+			},
+			options: &BuilderOptions{
+				Mode: BuilderModeList,
+			},
+			wanted:  "",
+			wantErr: `"on": labeled field in list-only builder`,
+		},
 		{
 			name: "error: odd number of arguments to LabeledDict",
 			builder: func(b *Builder) *Builder {
@@ -175,6 +365,23 @@ func TestBuilder(t *testing.T) {
 			wanted:  "",
 			wantErr: `"invalid field": invalid field name`,
 		},
+		{
+			name: "parsed composites in lists are grouped",
+			builder: func(b *Builder) *Builder {
+				return b.LabeledList("l", "a",
+					ListValue{[]Value{IdentifierValue{"b"}, IdentifierValue{"c"}}},
+					MapValue{[]Value{IdentifierValue{"k"}}, []Value{IdentifierValue{"v"}}})
+			},
+			wanted: "l=a;(b;c);(k:v)",
+		},
+		{
+			name: "error: map as a map key",
+			builder: func(b *Builder) *Builder {
+				return b.LabeledDict("d", MapValue{[]Value{IdentifierValue{"k"}}, []Value{IdentifierValue{"v"}}}, "x")
+			},
+			wanted:  "",
+			wantErr: "list or map cannot be a map key",
+		},
 	}
 
 	for _, tt := range tests {
@@ -191,6 +398,10 @@ func TestBuilder(t *testing.T) {
 				t.Errorf("expected: %q, got: %q", tt.wanted, result)
 			}
 
+			if size := builder.Size(); size != len(result) {
+				t.Errorf("Size() = %d, len(String()) = %d", size, len(result))
+			}
+
 			if err := builder.Err(); (err != nil) != (tt.wantErr != "") {
 				t.Errorf("expected error: %v, got: %v", tt.wantErr, err)
 			}
@@ -216,7 +427,17 @@ func TestNeedsQuoting(t *testing.T) {
 		{"nil", true},         // Keyword
 		{"abc123", false},     // Alphanumeric
 		{"abc-123", false},    // With hyphen
-		{"123abc", false},     // Starts with number
+		{"123abc", true},      // Lexes as a number followed by an identifier
+		{"123", false},        // Number
+		{"-1.5", false},       // Signed number
+		{"-", true},           // Lone sign
+		{"^abc", true},        // Contains boolean prefix
+		{"a^b", true},         // Contains boolean prefix
+		{"!abc", true},        // Contains boolean prefix
+		{"a!b", true},         // Contains boolean prefix
+		{"#abc", true},        // Contains hash
+		{"a#b", true},         // Contains hash
+		{"(a)", true},         // Contains parentheses
 	}
 
 	for _, tt := range tests {
@@ -228,3 +449,731 @@ func TestNeedsQuoting(t *testing.T) {
 		})
 	}
 }
+
+func TestBuilder_LabeledPairs(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairs   []any
+		kvs     []KV
+		options BuilderOptions
+	}{
+		{
+			name:  "empty",
+			pairs: nil,
+			kvs:   nil,
+		},
+		{
+			name:  "mixed values",
+			pairs: []any{"theme", "dark", "fontSize", 14, "autoSave", true},
+			kvs:   []KV{{"theme", "dark"}, {"fontSize", 14}, {"autoSave", true}},
+		},
+		{
+			name:  "quoted values",
+			pairs: []any{"a b", "hello world", "empty", ""},
+			kvs:   []KV{{"a b", "hello world"}, {"empty", ""}},
+		},
+		{
+			name:    "with spacing",
+			pairs:   []any{"theme", "dark", "fontSize", 14},
+			kvs:     []KV{{"theme", "dark"}, {"fontSize", 14}},
+			options: BuilderOptions{SpaceAfterListSeparator: true, SpaceAfterPairsSeparator: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wanted := NewBuilder(tt.options).LabeledDict("settings", tt.pairs...).String()
+			got := NewBuilder(tt.options).LabeledPairs("settings", tt.kvs...).String()
+
+			if got != wanted {
+				t.Errorf("LabeledPairs() = %q, LabeledDict() = %q", got, wanted)
+			}
+		})
+	}
+}
+
+func TestBuilder_AppendLabeledIfSet(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  Value
+		wanted string
+	}{
+		{"nil interface", nil, "a=1"},
+		{"nil value", NilValue{}, "a=1"},
+		{"zero value", ZeroValue{}, "a=1"},
+		{"false", BooleanValue{"false"}, "a=1"},
+		{"zero", NumberValue{"0.0"}, "a=1"},
+		{"empty string", StringValue{raw: `""`}, "a=1"},
+		{"empty list", ListValue{}, "a=1"},
+		{"true", BooleanValue{"true"}, "a=1,v=true"},
+		{"number", NumberValue{"0x10"}, "a=1,v=0x10"},
+		{"identifier", IdentifierValue{"dark"}, "a=1,v=dark"},
+		{"string", StringValue{raw: `"hello world"`}, `a=1,v="hello world"`},
+		{"list", ListValue{[]Value{IdentifierValue{"dev"}, IdentifierValue{"prod"}}}, "a=1,v=dev;prod"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewBuilder().Labeled("a", 1).AppendLabeledIfSet("v", tt.value).String()
+			if got != tt.wanted {
+				t.Errorf("AppendLabeledIfSet() = %q, want %q", got, tt.wanted)
+			}
+		})
+	}
+}
+
+func TestTokensToString(t *testing.T) {
+	tok := func(typ TokenType, val string) Token {
+		return Token{Typ: typ, Val: val}
+	}
+
+	tests := []struct {
+		name    string
+		tokens  []Token
+		wanted  string
+		wantErr bool
+	}{
+		{"empty", nil, "", false},
+		{"labeled fields", []Token{
+			tok(TokenBooleanPrefix, "^"), tok(TokenIdentifier, "on"), tok(TokenFieldSeparator, ","),
+			tok(TokenIdentifier, "tags"), tok(TokenAssign, "="), tok(TokenIdentifier, "dev"),
+			tok(TokenListSeparator, ";"), tok(TokenString, `'prod env'`), tok(TokenEOF, ""),
+		}, `^on,tags=dev;'prod env'`, false},
+		{"adjacent identifiers", []Token{
+			tok(TokenIdentifier, "a"), tok(TokenIdentifier, "b"),
+		}, "a b", false},
+		{"identifier before signed number", []Token{
+			tok(TokenIdentifier, "a"), tok(TokenNumber, "-1"),
+		}, "a -1", false},
+		{"number before exponent-like identifier", []Token{
+			tok(TokenNumber, "1"), tok(TokenIdentifier, "e5"), tok(TokenTrue, "true"), tok(TokenNil, "nil"),
+		}, "1 e5 true nil", false},
+		{"strings need no space", []Token{
+			tok(TokenIdentifier, "a"), tok(TokenString, `"b"`), tok(TokenNumber, "1"),
+		}, `a"b"1`, false},
+		{"identifier needing quotes", []Token{
+			tok(TokenIdentifier, "a b"),
+		}, "", true},
+		{"keyword as identifier", []Token{
+			tok(TokenIdentifier, "true"),
+		}, "", true},
+		{"error token", []Token{
+			tok(TokenError, "unexpected character"),
+		}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TokensToString(tt.tokens)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TokensToString() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.wanted {
+				t.Errorf("TokensToString() = %q, want %q", got, tt.wanted)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			// The output must lex back to the same tokens.
+			var want, relexed []Token
+			for _, tok := range tt.tokens {
+				if tok.Typ != TokenEOF {
+					want = append(want, Token{Typ: tok.Typ, Val: tok.Val})
+				}
+			}
+			for tok := range Lex(got) {
+				if tok.Typ != TokenEOF {
+					relexed = append(relexed, Token{Typ: tok.Typ, Val: tok.Val})
+				}
+			}
+			if !slices.Equal(relexed, want) {
+				t.Errorf("Lex(%q) = %v, want %v", got, relexed, want)
+			}
+		})
+	}
+}
+
+func TestBuilder_PromoteOrderedToKeyed(t *testing.T) {
+	b := NewBuilder(BuilderOptions{PromoteOrderedToKeyed: true}).
+		Labeled("a", 1).
+		Value(2)
+	if err := b.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	got := b.String()
+	if got != "a=1,0=2" {
+		t.Fatalf("String() = %q, want %q", got, "a=1,0=2")
+	}
+
+	// The promoted keys do not parse back.
+	var event ErrorEvent
+	if _, err := ParseAll(got); !errors.As(err, &event) || event.Msg != "ordered value not allowed here" {
+		t.Errorf("ParseAll() error = %v, want ordered value not allowed here", err)
+	}
+}
+
+func TestBuilder_NestedValueRoundTrip(t *testing.T) {
+	list := ListValue{[]Value{IdentifierValue{"a"}, IdentifierValue{"b"}}}
+	dict := MapValue{[]Value{IdentifierValue{"k"}, IdentifierValue{"j"}}, []Value{IdentifierValue{"v"}, IdentifierValue{"w"}}}
+
+	got, err := NewBuilder().LabeledDict("d", "l", list, "m", dict).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	doc, err := Load(got)
+	if err != nil {
+		t.Fatalf("Load(%q) error = %v", got, err)
+	}
+	d, ok := doc.GetDict("d")
+	if !ok {
+		t.Fatalf("GetDict() not found in %q", got)
+	}
+	if l, ok := d.GetList("l"); !ok || len(l) != 2 {
+		t.Errorf("GetList() = %v, %t, want 2 items", l, ok)
+	}
+	m, ok := d.GetDict("m")
+	if !ok {
+		t.Fatalf("GetDict() not found in %q", got)
+	}
+	if w, ok := m.GetString("j"); !ok || w != "w" {
+		t.Errorf("GetString() = %q, %t, want w", w, ok)
+	}
+}
+
+func TestBuilder_DictKeyRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    any
+		wanted string
+	}{
+		{"key with space", "a b", `m="a b":x`},
+		{"key with separators", "a:b;c", `m="a:b;c":x`},
+		{"keyword key", "true", `m="true":x`},
+		{"key with quotes", `say "hi"`, `m="say \"hi\"":x`},
+		{"empty key", "", `m="":x`},
+		{"number key", 10, `m=10:x`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewBuilder().LabeledDict("m", tt.key, "x").String()
+			if got != tt.wanted {
+				t.Fatalf("LabeledDict() = %q, want %q", got, tt.wanted)
+			}
+
+			m, err := ToMap(got)
+			if err != nil {
+				t.Fatalf("ToMap(%q) error = %v", got, err)
+			}
+			dict, ok := As[MapValue](m["m"])
+			if !ok {
+				t.Fatalf("ToMap(%q)[m] = %#v, want MapValue", got, m["m"])
+			}
+
+			key := fmt.Sprint(tt.key)
+			if v, ok := dict.Get(key); !ok || v.Raw() != "x" {
+				t.Errorf("Get(%q) = %v, %t, want x", key, v, ok)
+			}
+		})
+	}
+}
+
+func TestBuilder_AppendParsed(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder func(b *Builder) *Builder
+		input   string
+		wanted  string
+		wantErr bool
+	}{
+		{
+			name:    "labeled fields",
+			builder: func(b *Builder) *Builder { return b.Labeled("x", 0) },
+			input:   "a=1,b=2",
+			wanted:  "x=0,a=1,b=2",
+		},
+		{
+			name:    "ordered values and composites",
+			builder: func(b *Builder) *Builder { return b.Value("first") },
+			input:   `second, ^on, name='john doe', tags=dev;prod, m=k:v;"n m":0x1F`,
+			wanted:  `first,second,on=true,name="john doe",tags=dev;prod,m=k:v;"n m":0x1F`,
+		},
+		{
+			name:    "empty input",
+			builder: func(b *Builder) *Builder { return b.Labeled("x", 0) },
+			input:   "",
+			wanted:  "x=0",
+		},
+		{
+			name:    "parse error keeps the builder",
+			builder: func(b *Builder) *Builder { return b.Labeled("x", 0) },
+			input:   "a=1,b==",
+			wanted:  "x=0",
+			wantErr: true,
+		},
+		{
+			name:    "error: ordered value after labeled field",
+			builder: func(b *Builder) *Builder { return b.Labeled("x", 0) },
+			input:   "a",
+			wanted:  "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.builder(NewBuilder())
+
+			if err := b.AppendParsed(tt.input); (err != nil) != tt.wantErr {
+				t.Errorf("AppendParsed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := b.String(); got != tt.wanted {
+				t.Errorf("String() = %q, want %q", got, tt.wanted)
+			}
+		})
+	}
+
+	t.Run("custom separators round trip", func(t *testing.T) {
+		lex := LexOptions{ListSeparator: "|", PairSeparator: "::"}
+		b := NewBuilder(BuilderOptions{Lex: lex}).Labeled("x", 0)
+
+		if err := b.AppendParsed("l=a|b,m=a::1|b::(x|y)", ParseOptions{Lex: lex}); err != nil {
+			t.Fatalf("AppendParsed() error = %v", err)
+		}
+		if got, want := b.String(), "x=0,l=a|b,m=a::1|b::(x|y)"; got != want {
+			t.Errorf("String() = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		options *BuilderOptions
+		wanted  string
+	}{
+		{"zero and nil values", "a=,b=nil", nil, "a=,b=nil"},
+		{"empty input", "", nil, ""},
+		{"ordered values", `x, 'y z', a=1`, nil, `x,"y z",a=1`},
+		{"composites", "tags=dev;prod, m=k:v;n:nil", nil, "tags=dev;prod,m=k:v;n:nil"},
+		{"groups", "m=db:( host:x; port:1 );tags:(a;b)", nil, "m=db:(host:x;port:1);tags:(a;b)"},
+		{"with options", "a=,b=1;2", &BuilderOptions{SpaceAfterFieldSeparator: true, SpaceAroundFieldAssignment: true}, "a = , b = 1;2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []BuilderOptions
+			if tt.options != nil {
+				opts = append(opts, *tt.options)
+			}
+
+			got, err := Encode(Parse(tt.input), opts...)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if got != tt.wanted {
+				t.Errorf("Encode() = %q, want %q", got, tt.wanted)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		if _, err := Encode(Parse("a==")); err == nil {
+			t.Errorf("Encode() expected error, got nil")
+		}
+	})
+
+	t.Run("custom separators round trip", func(t *testing.T) {
+		lex := LexOptions{ListSeparator: "|", PairSeparator: "::"}
+		input := "l=a|b,m=a::1|b::(x|y)"
+
+		got, err := Encode(Parse(input, ParseOptions{Lex: lex}), BuilderOptions{Lex: lex})
+		if err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		if got != input {
+			t.Errorf("Encode() = %q, want %q", got, input)
+		}
+	})
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		options *BuilderOptions
+		wanted  string
+	}{
+		{"quotes are kept by default", `name="john"`, nil, `name="john"`},
+		{"spacing", `a = 1 ,  b=x # note`, nil, "a=1,b=x"},
+		{"minimal quoting", `name="john", s='john doe'`, &BuilderOptions{MinimalQuoting: true}, `name=john,s="john doe"`},
+		{"minimal quoting keeps keywords", `a="true",b="nil"`, &BuilderOptions{MinimalQuoting: true}, `a="true",b="nil"`},
+		{"minimal quoting keeps numbers", `a="42",b=""`, &BuilderOptions{MinimalQuoting: true}, `a="42",b=""`},
+		{"minimal quoting keeps separators", `a="x;y",b="k:v",c="a=b"`, &BuilderOptions{MinimalQuoting: true}, `a="x;y",b="k:v",c="a=b"`},
+		{"minimal quoting in composites", `l="a";"b c",m="k":"v"`, &BuilderOptions{MinimalQuoting: true}, `l=a;"b c",m=k:v`},
+		{"minimal quoting with custom separators", `a="x",b="x|y"`, &BuilderOptions{MinimalQuoting: true, Lex: LexOptions{ListSeparator: "|"}}, `a=x,b="x|y"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []BuilderOptions
+			if tt.options != nil {
+				opts = append(opts, *tt.options)
+			}
+
+			got, err := Format(tt.input, opts...)
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+			if got != tt.wanted {
+				t.Errorf("Format() = %q, want %q", got, tt.wanted)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		if _, err := Format("a=="); err == nil {
+			t.Errorf("Format() expected error, got nil")
+		}
+	})
+
+	t.Run("custom separators round trip", func(t *testing.T) {
+		opt := BuilderOptions{Lex: LexOptions{ListSeparator: "|", PairSeparator: "::"}}
+		input := "l=a|b,m=a::1|b::(x|y)"
+
+		got, err := Format(input, opt)
+		if err != nil {
+			t.Fatalf("Format() error = %v", err)
+		}
+		if got != input {
+			t.Errorf("Format() = %q, want %q", got, input)
+		}
+		if errs := Validate(got, ParseOptions{Lex: opt.Lex}); len(errs) > 0 {
+			t.Errorf("Validate() = %v, want no errors", errs)
+		}
+	})
+}
+
+func TestDetectQuoteStyle(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected byte
+	}{
+		{"double heavy", `a="x",b="y",c='z'`, '"'},
+		{"single heavy", `a='x',b='y',c="z"`, '\''},
+		{"tie", `a='x',b="y"`, '"'},
+		{"no strings", `a=1,b=x`, '"'},
+		{"list and map items", `l='a';'b',m='k':"v"`, '\''},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectQuoteStyle(tt.input)
+			if err != nil {
+				t.Fatalf("DetectQuoteStyle() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("DetectQuoteStyle() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		if _, err := DetectQuoteStyle(`a="x`); err == nil {
+			t.Errorf("DetectQuoteStyle() expected error, got nil")
+		}
+	})
+}
+
+func TestBuilder_Floats(t *testing.T) {
+	tests := []struct {
+		value  any
+		wanted string
+	}{
+		{1000000.0, "1e+06"},
+		{0.0001, "0.0001"},
+		{1.5e-300, "1.5e-300"},
+		{-2.25, "-2.25"},
+		{42.0, "42"},
+		{float32(0.1), "0.1"},
+		{math.Inf(1), `"+Inf"`},
+		{math.NaN(), `"NaN"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wanted, func(t *testing.T) {
+			got := NewBuilder().Labeled("f", tt.value).String()
+			if want := "f=" + tt.wanted; got != want {
+				t.Fatalf("Builder.String() = %q, want %q", got, want)
+			}
+
+			f, ok := tt.value.(float64)
+			if !ok {
+				return
+			}
+
+			m, err := ToMap(got)
+			if err != nil {
+				t.Fatalf("ToMap() error = %v", err)
+			}
+			if math.IsInf(f, 0) || math.IsNaN(f) {
+				// Non-finite values read back as strings.
+				if m["f"].Type() != StringValueType {
+					t.Errorf("Type() = %s, want %s", m["f"].Type(), StringValueType)
+				}
+				return
+			}
+			back, err := ToFloat(m["f"])
+			if err != nil {
+				t.Fatalf("ToFloat() error = %v", err)
+			}
+			if back != f {
+				t.Errorf("round trip = %v, want %v", back, f)
+			}
+		})
+	}
+}
+
+func TestBuilder_Build(t *testing.T) {
+	got, err := NewBuilder().Value("x").Labeled("a", 1).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if got != "x,a=1" {
+		t.Errorf("Build() = %q, want %q", got, "x,a=1")
+	}
+
+	got, err = NewBuilder().Labeled("invalid field", "value").Build()
+	if !errors.Is(err, ErrInvalidFieldName) {
+		t.Errorf("Build() error = %v, want %v", err, ErrInvalidFieldName)
+	}
+	if got != "" {
+		t.Errorf("Build() = %q, want empty string on error", got)
+	}
+}
+
+func TestBuilder_LabeledBytes(t *testing.T) {
+	data := make([]byte, 256)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	s, err := NewBuilder().LabeledBytes("blob", data).LabeledBytes("empty", nil).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	m, err := ToMap(s)
+	if err != nil {
+		t.Fatalf("ToMap(%q) error = %v", s, err)
+	}
+
+	got, err := ToBytes(m["blob"])
+	if err != nil {
+		t.Fatalf("ToBytes() error = %v", err)
+	}
+	if !slices.Equal(got, data) {
+		t.Errorf("ToBytes() = %v, want %v", got, data)
+	}
+
+	if got, err := ToBytes(m["empty"]); err != nil || len(got) != 0 {
+		t.Errorf("ToBytes() = %v, %v, want empty bytes", got, err)
+	}
+}
+
+func TestBuilder_LabeledListOfDicts(t *testing.T) {
+	rows := []map[string]any{
+		{"port": 1, "name": "a"},
+		{"name": "b c", "port": 2},
+	}
+
+	got, err := NewBuilder().Labeled("x", 1).LabeledListOfDicts("rows", rows).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if want := `x=1,rows=(name:a;port:1);(name:"b c";port:2)`; got != want {
+		t.Fatalf("Build() = %q, want %q", got, want)
+	}
+
+	doc, err := Load(got)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	list, ok := doc.GetList("rows")
+	if !ok || len(list) != 2 {
+		t.Fatalf("GetList() = %v, %t, want 2 rows", list, ok)
+	}
+	for i, row := range list {
+		m, ok := As[MapValue](row)
+		if !ok {
+			t.Fatalf("row %d = %v, want a map", i, row)
+		}
+		port, _ := m.Get("port")
+		if n, err := ToInt(port); err != nil || n != int64(i+1) {
+			t.Errorf("row %d port = %d, %v, want %d", i, n, err, i+1)
+		}
+	}
+
+	t.Run("empty row", func(t *testing.T) {
+		_, err := NewBuilder().LabeledListOfDicts("rows", []map[string]any{{}}).Build()
+		if !errors.Is(err, ErrEmptyRow) {
+			t.Errorf("Build() error = %v, want %v", err, ErrEmptyRow)
+		}
+	})
+}
+
+func TestBuilder_BooleanStyle(t *testing.T) {
+	tests := []struct {
+		name   string
+		style  BooleanStyle
+		wanted string
+	}{
+		{"prefix", BooleanStylePrefix, "^enabled,!debug"},
+		{"explicit", BooleanStyleExplicit, "enabled=true,debug=false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewBuilder(BuilderOptions{BooleanStyle: tt.style}).
+				Boolean("enabled", true).
+				Boolean("debug", false).
+				Build()
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if got != tt.wanted {
+				t.Fatalf("Build() = %q, want %q", got, tt.wanted)
+			}
+
+			m, err := ToMap(got)
+			if err != nil {
+				t.Fatalf("ToMap() error = %v", err)
+			}
+			for key, want := range map[string]bool{"enabled": true, "debug": false} {
+				if v, err := ToBool(m[key]); err != nil || v != want {
+					t.Errorf("ToBool(%s) = %v, %v, want %v", key, v, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuilder_LabeledDictOfValues(t *testing.T) {
+	m := map[string]Value{
+		"port": NumberValue{"8080"},
+		"host": StringValue{raw: `"example.com"`},
+		"name": StringValue{raw: `"a b"`, src: `'a b'`},
+		"tags": ListValue{[]Value{IdentifierValue{"x"}, IdentifierValue{"y"}}},
+		"none": nil,
+	}
+
+	got, err := NewBuilder().LabeledDictOfValues("server", m).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	want := `server=host:"example.com";name:"a b";none:nil;port:8080;tags:(x;y)`
+	if got != want {
+		t.Fatalf("Build() = %q, want %q", got, want)
+	}
+
+	doc, err := Load(got)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	server, ok := doc.GetDict("server")
+	if !ok {
+		t.Fatalf("GetDict() not found")
+	}
+	if port, ok := server.GetInt("port"); !ok || port != 8080 {
+		t.Errorf("GetInt() = %d, %t, want 8080", port, ok)
+	}
+	if tags, ok := server.GetList("tags"); !ok || len(tags) != 2 {
+		t.Errorf("GetList() = %v, %t, want 2 items", tags, ok)
+	}
+
+	t.Run("custom separators round trip", func(t *testing.T) {
+		lex := LexOptions{ListSeparator: "|", PairSeparator: "::"}
+		m := map[string]Value{
+			"port": NumberValue{"8080"},
+			"tags": ListValue{[]Value{IdentifierValue{"x"}, IdentifierValue{"y"}}},
+		}
+
+		got, err := NewBuilder(BuilderOptions{Lex: lex}).LabeledDictOfValues("server", m).Build()
+		if err != nil {
+			t.Fatalf("Build() error = %v", err)
+		}
+		if want := "server=port::8080|tags::(x|y)"; got != want {
+			t.Fatalf("Build() = %q, want %q", got, want)
+		}
+
+		doc, err := Load(got, ParseOptions{Lex: lex})
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		server, ok := doc.GetDict("server")
+		if !ok {
+			t.Fatalf("GetDict() not found")
+		}
+		if tags, ok := server.GetList("tags"); !ok || len(tags) != 2 {
+			t.Errorf("GetList() = %v, %t, want 2 items", tags, ok)
+		}
+	})
+}
+
+func TestBuilder_MaxLineLength(t *testing.T) {
+	build := func(opts BuilderOptions) *Builder {
+		return NewBuilder(opts).
+			Labeled("name", "john doe").
+			Labeled("host", "localhost").
+			Enable("enabled").
+			LabeledList("tags", "a", "b", "c").
+			Labeled("port", 8080)
+	}
+
+	tests := []struct {
+		name    string
+		options BuilderOptions
+		wanted  string
+	}{
+		{"no limit", BuilderOptions{}, `name="john doe",host=localhost,^enabled,tags=a;b;c,port=8080`},
+		{"small width", BuilderOptions{MaxLineLength: 20},
+			"name=\"john doe\",host=localhost,\n^enabled,tags=a;b;c,port=8080"},
+		{"width below each field", BuilderOptions{MaxLineLength: 1},
+			"name=\"john doe\",\nhost=localhost,\n^enabled,\ntags=a;b;c,\nport=8080"},
+		{"space after separator", BuilderOptions{MaxLineLength: 20, SpaceAfterFieldSeparator: true},
+			"name=\"john doe\", host=localhost,\n^enabled, tags=a;b;c, port=8080"},
+	}
+
+	want, err := ParseAll(tests[0].wanted)
+	if err != nil {
+		t.Fatalf("ParseAll() error = %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := build(tt.options)
+			got, err := b.Build()
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if got != tt.wanted {
+				t.Errorf("Build() = %q, want %q", got, tt.wanted)
+			}
+			if size := b.Size(); size != len(got) {
+				t.Errorf("Size() = %d, len(String()) = %d", size, len(got))
+			}
+
+			events, err := ParseAll(got)
+			if err != nil {
+				t.Fatalf("ParseAll() error = %v", err)
+			}
+			if !reflect.DeepEqual(events, want) {
+				t.Errorf("ParseAll() = %v, want %v", events, want)
+			}
+		})
+	}
+}
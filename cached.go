@@ -0,0 +1,51 @@
+package kaval
+
+// CachedValue wraps a Value and memoizes the results of its conversions.
+//
+// Each conversion is computed on first use and returned from the cache on
+// every later call, which avoids parsing the same value repeatedly. A
+// CachedValue is not safe for concurrent use.
+type CachedValue struct {
+	value Value
+
+	str  cachedResult[string]
+	int  cachedResult[int64]
+	uint cachedResult[uint64]
+	flt  cachedResult[float64]
+	bool cachedResult[bool]
+}
+
+// cachedResult holds the lazily computed result of a conversion.
+type cachedResult[T any] struct {
+	done bool
+	val  T
+	err  error
+}
+
+// get returns the cached result, computing it with conv on first use.
+func (c *cachedResult[T]) get(v Value, conv func(Value) (T, error)) (T, error) {
+	if !c.done {
+		c.val, c.err = conv(v)
+		c.done = true
+	}
+	return c.val, c.err
+}
+
+// NewCachedValue returns a CachedValue for v. Events carrying a Value, such
+// as a ValueEvent, are unwrapped.
+func NewCachedValue(v Value) *CachedValue {
+	if ev, ok := v.(interface{ Unwrap() Value }); ok {
+		v = ev.Unwrap()
+	}
+	return &CachedValue{value: v}
+}
+
+func (v *CachedValue) Type() ValueType { return v.value.Type() }
+func (v *CachedValue) Raw() string     { return v.value.Raw() }
+func (v *CachedValue) Unwrap() Value   { return v.value }
+
+func (v *CachedValue) ToString() (string, error) { return v.str.get(v.value, ToString) }
+func (v *CachedValue) ToInt() (int64, error)     { return v.int.get(v.value, ToInt) }
+func (v *CachedValue) ToUint() (uint64, error)   { return v.uint.get(v.value, ToUint) }
+func (v *CachedValue) ToFloat() (float64, error) { return v.flt.get(v.value, ToFloat) }
+func (v *CachedValue) ToBool() (bool, error)     { return v.bool.get(v.value, ToBool) }
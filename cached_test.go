@@ -0,0 +1,76 @@
+package kaval
+
+import (
+	"testing"
+)
+
+func TestCachedValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value Value
+
+		wantString *string
+		wantInt    *int64
+		wantUint   *uint64
+		wantFloat  *float64
+		wantBool   *bool
+	}{
+		{name: "number", value: NumberValue{"0x2A"}, wantInt: p(int64(42)), wantUint: p(uint64(42)), wantFloat: p(float64(42))},
+		{name: "float", value: NumberValue{"1.5e3"}, wantFloat: p(float64(1500))},
+		{name: "string", value: StringValue{raw: `"hello"`}, wantString: p("hello")},
+		{name: "boolean", value: BooleanValue{"true"}, wantBool: p(true)},
+		{name: "value event", value: ValueEvent{NumberValue{"-7"}}, wantInt: p(int64(-7)), wantFloat: p(float64(-7))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewCachedValue(tt.value)
+
+			// Convert twice to cover both the computed and the cached result.
+			for range 2 {
+				testConversion(t, "ToString", tt.wantString, v, ToString)
+				testConversion(t, "ToInt", tt.wantInt, v, ToInt)
+				testConversion(t, "ToUint", tt.wantUint, v, ToUint)
+				testConversion(t, "ToFloat", tt.wantFloat, v, ToFloat)
+				testConversion(t, "ToBool", tt.wantBool, v, ToBool)
+			}
+
+			if v.Type() != tt.value.Type() || v.Raw() != tt.value.Raw() {
+				t.Errorf("CachedValue = %s %q, want %s %q", v.Type(), v.Raw(), tt.value.Type(), tt.value.Raw())
+			}
+		})
+	}
+}
+
+func TestCachedValue_Unwrap(t *testing.T) {
+	v := NewCachedValue(StringValue{raw: `""`})
+
+	if !IsNil(v) {
+		t.Errorf("IsNil() = false, want true")
+	}
+	if _, ok := As[StringValue](v); !ok {
+		t.Errorf("As[StringValue]() failed")
+	}
+}
+
+func BenchmarkCachedValue(b *testing.B) {
+	const conversions = 4
+	value := NumberValue{"12_345.678e-3"}
+
+	b.Run("uncached", func(b *testing.B) {
+		for b.Loop() {
+			for range conversions {
+				_, _ = ToFloat(value)
+			}
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		for b.Loop() {
+			v := NewCachedValue(value)
+			for range conversions {
+				_, _ = ToFloat(v)
+			}
+		}
+	})
+}
@@ -0,0 +1,335 @@
+package kaval
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	convertersMu sync.RWMutex
+	converters   = map[any]any{}
+)
+
+// converterKey returns the registry key for the type T.
+//
+// A typed nil pointer is comparable and distinct for every T, which lets
+// the registry be keyed by type without resorting to reflection.
+func converterKey[T any]() any {
+	return (*T)(nil)
+}
+
+// RegisterConverter registers fn as the conversion used by Convert for the
+// type T. Registering a converter for the same type again replaces it.
+func RegisterConverter[T any](fn func(Value) (T, error)) {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+
+	converters[converterKey[T]()] = fn
+}
+
+// lookupConverter returns the converter registered for the type T, if any.
+func lookupConverter[T any]() (func(Value) (T, error), bool) {
+	convertersMu.RLock()
+	defer convertersMu.RUnlock()
+
+	fn, ok := converters[converterKey[T]()]
+	if !ok {
+		return nil, false
+	}
+	return fn.(func(Value) (T, error)), true
+}
+
+// Convert attempts to convert a Value to the type T.
+//
+// Converters registered with RegisterConverter take precedence. Otherwise,
+// the built-in conversions are used for string, int64, uint64, float64 and
+// bool.
+func Convert[T any](v Value) (T, error) {
+	if fn, ok := lookupConverter[T](); ok {
+		return fn(v)
+	}
+	return convertBuiltIn[T](v)
+}
+
+// Coerce converts a Value to one of the built-in target types of Convert,
+// checking the type T at compile time. Registered converters are not used.
+func Coerce[T int64 | uint64 | float64 | string | bool](v Value) (T, error) {
+	return convertBuiltIn[T](v)
+}
+
+// convertBuiltIn converts a Value with the built-in conversion for T.
+func convertBuiltIn[T any](v Value) (T, error) {
+	var (
+		zero T
+		out  any
+		err  error
+	)
+	switch any(zero).(type) {
+	case string:
+		out, err = ToString(v)
+	case int64:
+		out, err = ToInt(v)
+	case uint64:
+		out, err = ToUint(v)
+	case float64:
+		out, err = ToFloat(v)
+	case bool:
+		out, err = ToBool(v)
+	default:
+		return zero, fmt.Errorf("no converter registered for %T", zero)
+	}
+	if err != nil {
+		return zero, err
+	}
+	return out.(T), nil
+}
+
+// ToStringEvent attempts to convert the Value of a ValueEvent or MapKeyEvent
+// to a string value.
+func ToStringEvent(e ParserEvent) (string, error) { return convertEvent(e, ToString) }
+
+// ToFloatEvent attempts to convert the Value of a ValueEvent or MapKeyEvent
+// to a float value.
+func ToFloatEvent(e ParserEvent) (float64, error) { return convertEvent(e, ToFloat) }
+
+// ToIntEvent attempts to convert the Value of a ValueEvent or MapKeyEvent to
+// an int64 value.
+func ToIntEvent(e ParserEvent) (int64, error) { return convertEvent(e, ToInt) }
+
+// ToUintEvent attempts to convert the Value of a ValueEvent or MapKeyEvent to
+// an uint64 value.
+func ToUintEvent(e ParserEvent) (uint64, error) { return convertEvent(e, ToUint) }
+
+// ToBoolEvent attempts to convert the Value of a ValueEvent or MapKeyEvent to
+// a boolean value.
+func ToBoolEvent(e ParserEvent) (bool, error) { return convertEvent(e, ToBool) }
+
+// convertEvent unwraps the Value of an event and converts it with conv.
+func convertEvent[T any](e ParserEvent, conv func(Value) (T, error)) (T, error) {
+	ev, ok := e.(interface{ Unwrap() Value })
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("event %T carries no value", e)
+	}
+	return conv(ev.Unwrap())
+}
+
+// ToBytes attempts to convert a Value holding base64 encoded text, such as
+// one written with Builder.LabeledBytes, to the bytes it encodes.
+func ToBytes(v Value) ([]byte, error) {
+	if conv, ok := As[interface{ ToBytes() ([]byte, error) }](v); ok {
+		return conv.ToBytes()
+	}
+
+	s, err := ToString(v)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(s)
+}
+
+// ToBoolLenient attempts to convert a Value to a bool like ToBool, but also
+// accepts the numbers 1 for true and 0 for false, e.g. enabled=1.
+func ToBoolLenient(v Value) (bool, error) {
+	if n, ok := As[NumberValue](v); ok {
+		switch n.Raw() {
+		case "1":
+			return true, nil
+		case "0":
+			return false, nil
+		default:
+			return false, fmt.Errorf("invalid boolean: %s", n.Raw())
+		}
+	}
+	return ToBool(v)
+}
+
+// ToStringLenient attempts to convert a Value to a string like ToString,
+// but also accepts numbers as their text, e.g. "8080" for port=8080.
+func ToStringLenient(v Value) (string, error) {
+	if n, ok := As[NumberValue](v); ok {
+		return n.Raw(), nil
+	}
+	return ToString(v)
+}
+
+// ToStrings attempts to convert a list to the strings of its items. A single
+// value is returned as a list of one item and an empty assignment or a
+// missing field as an empty list, e.g. for fields that are sometimes written
+// as a list. Numbers and booleans are accepted as their text like
+// ToStringLenient, only maps and nil are rejected.
+func ToStrings(v Value) ([]string, error) {
+	switch val := v.(type) {
+	case ListValue:
+		items := make([]string, len(val.items))
+		for i, item := range val.items {
+			s, err := scalarString(item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			items[i] = s
+		}
+		return items, nil
+	case ZeroValue, UndefinedValue:
+		return []string{}, nil
+	case MapValue:
+		return nil, fmt.Errorf("value of type %s is not string-convertible", v.Type())
+	default:
+		s, err := scalarString(v)
+		if err != nil {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+}
+
+// scalarString converts a scalar Value to a string like ToStringLenient,
+// also accepting booleans as their text.
+func scalarString(v Value) (string, error) {
+	if b, ok := As[BooleanValue](v); ok {
+		return b.Raw(), nil
+	}
+	return ToStringLenient(v)
+}
+
+// ToPointer attempts to convert a Value with conv, keeping apart nil and
+// empty assignments: name=nil and a missing field return a nil pointer,
+// name= returns a pointer to the zero value of T, and any other value a
+// pointer to its conversion.
+func ToPointer[T any](v Value, conv func(Value) (T, error)) (*T, error) {
+	if _, ok := As[NilValue](v); ok {
+		return nil, nil
+	}
+	if _, ok := As[UndefinedValue](v); ok {
+		return nil, nil
+	}
+	if _, ok := As[ZeroValue](v); ok {
+		return new(T), nil
+	}
+
+	out, err := conv(v)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ToFlagSet attempts to convert a map of boolean entries, e.g. ^a;!b, to
+// the boolean of each key. Keys are converted as by MapValue.ToMap.
+func ToFlagSet(v Value) (map[string]bool, error) {
+	m, ok := As[MapValue](v)
+	if !ok {
+		return nil, fmt.Errorf("value of type %s is not a map", v.Type())
+	}
+
+	flags := make(map[string]bool, m.Len())
+	for key, value := range m.Entries() {
+		b, err := ToBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("flag %s: %w", key.Raw(), err)
+		}
+		flags[keyString(key)] = b
+	}
+	return flags, nil
+}
+
+// ToURL attempts to convert a Value to a URL.
+func ToURL(v Value) (*url.URL, error) {
+	s, err := ToString(v)
+	if err != nil {
+		return nil, err
+	}
+	return url.Parse(s)
+}
+
+// ToTime attempts to convert a Value to a time using layout, e.g.
+// "2006-01-02" for created="2023-06-01".
+func ToTime(v Value, layout string) (time.Time, error) {
+	s, err := ToString(v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(layout, s)
+}
+
+// ToMAC attempts to convert a Value to a hardware address.
+//
+// The colons in addresses such as 00:11:22:33:44:55 are read as pair
+// separators by the lexer, so addresses must be written as quoted strings:
+// mac="00:11:22:33:44:55".
+func ToMAC(v Value) (net.HardwareAddr, error) {
+	s, err := ToString(v)
+	if err != nil {
+		return nil, err
+	}
+	return net.ParseMAC(s)
+}
+
+// ToNumberWithUnit attempts to split a Value into a leading decimal number
+// and a trailing unit, e.g. "75%" into 75 and "%" or "512MB" into 512 and
+// "MB". Whitespace between the number and the unit is ignored.
+//
+// Units are not valid in unquoted numbers, so such values are usually
+// written as strings: mem="512MB".
+func ToNumberWithUnit(v Value) (float64, string, error) {
+	s, err := ToString(v)
+	if err != nil {
+		// Numbers have no unit but are valid nonetheless.
+		n, ok := As[NumberValue](v)
+		if !ok {
+			return 0, "", err
+		}
+		f, err := n.ToFloat()
+		return f, "", err
+	}
+
+	n := numberPrefixLen(s)
+	if n == 0 {
+		return 0, "", fmt.Errorf("%q does not start with a number", s)
+	}
+
+	f, err := NumberValue{raw: s[:n]}.ToFloat()
+	if err != nil {
+		return 0, "", err
+	}
+	return f, strings.TrimSpace(s[n:]), nil
+}
+
+// numberPrefixLen returns the length of the decimal number at the start of s,
+// or 0 if s does not start with one.
+func numberPrefixLen(s string) int {
+	i := 0
+	if i < len(s) && isNumericSign(rune(s[i])) {
+		i++
+	}
+
+	digits := 0
+	for ; i < len(s) && (isDigit(rune(s[i])) || s[i] == '_' || s[i] == '.'); i++ {
+		if isDigit(rune(s[i])) {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return 0
+	}
+
+	// Only consume an exponent that is followed by digits, e.g. "5em" is
+	// the number 5 with the unit "em".
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && isNumericSign(rune(s[j])) {
+			j++
+		}
+		if j < len(s) && isDigit(rune(s[j])) {
+			for i = j; i < len(s) && (isDigit(rune(s[i])) || s[i] == '_'); {
+				i++
+			}
+		}
+	}
+	return i
+}
@@ -0,0 +1,492 @@
+package kaval
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testColor int
+
+const (
+	testColorRed testColor = iota + 1
+	testColorGreen
+)
+
+func TestConvert_Registered(t *testing.T) {
+	RegisterConverter(func(v Value) (testColor, error) {
+		s, err := ToString(v)
+		if err != nil {
+			return 0, err
+		}
+		switch s {
+		case "red":
+			return testColorRed, nil
+		case "green":
+			return testColorGreen, nil
+		default:
+			return 0, fmt.Errorf("unknown color: %s", s)
+		}
+	})
+
+	got, err := Convert[testColor](IdentifierValue{"red"})
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	if got != testColorRed {
+		t.Errorf("Convert() = %v, want %v", got, testColorRed)
+	}
+
+	if _, err := Convert[testColor](IdentifierValue{"blue"}); err == nil {
+		t.Errorf("Convert() expected error, got nil")
+	}
+}
+
+func TestConvert_BuiltIn(t *testing.T) {
+	if got, err := Convert[string](StringValue{raw: `"hello"`}); err != nil || got != "hello" {
+		t.Errorf("Convert[string]() = %q, %v, want %q", got, err, "hello")
+	}
+	if got, err := Convert[int64](NumberValue{"-42"}); err != nil || got != -42 {
+		t.Errorf("Convert[int64]() = %d, %v, want %d", got, err, -42)
+	}
+	if got, err := Convert[uint64](NumberValue{"0xFF"}); err != nil || got != 255 {
+		t.Errorf("Convert[uint64]() = %d, %v, want %d", got, err, 255)
+	}
+	if got, err := Convert[float64](NumberValue{"3.5"}); err != nil || got != 3.5 {
+		t.Errorf("Convert[float64]() = %v, %v, want %v", got, err, 3.5)
+	}
+	if got, err := Convert[bool](BooleanValue{"true"}); err != nil || !got {
+		t.Errorf("Convert[bool]() = %t, %v, want %t", got, err, true)
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	if got, err := Coerce[string](IdentifierValue{"hello"}); err != nil || got != "hello" {
+		t.Errorf("Coerce[string]() = %q, %v, want %q", got, err, "hello")
+	}
+	if got, err := Coerce[int64](NumberValue{"-0x10"}); err != nil || got != -16 {
+		t.Errorf("Coerce[int64]() = %d, %v, want %d", got, err, -16)
+	}
+	if got, err := Coerce[uint64](NumberValue{"42"}); err != nil || got != 42 {
+		t.Errorf("Coerce[uint64]() = %d, %v, want %d", got, err, 42)
+	}
+	if got, err := Coerce[float64](NumberValue{"1e3"}); err != nil || got != 1000 {
+		t.Errorf("Coerce[float64]() = %v, %v, want %v", got, err, 1000.0)
+	}
+	if got, err := Coerce[bool](BooleanValue{"false"}); err != nil || got {
+		t.Errorf("Coerce[bool]() = %t, %v, want %t", got, err, false)
+	}
+	if _, err := Coerce[int64](StringValue{raw: `"12"`}); err == nil {
+		t.Errorf("Coerce[int64]() expected error, got nil")
+	}
+}
+
+func TestConvert_Errors(t *testing.T) {
+	if _, err := Convert[int64](IdentifierValue{"abc"}); err == nil {
+		t.Errorf("Convert[int64]() expected error, got nil")
+	}
+	if _, err := Convert[complex128](NumberValue{"1"}); err == nil {
+		t.Errorf("Convert[complex128]() expected error, got nil")
+	}
+}
+
+func TestConvertEvent(t *testing.T) {
+	tests := []struct {
+		name       string
+		event      ParserEvent
+		wantString *string
+		wantInt    *int64
+		wantUint   *uint64
+		wantFloat  *float64
+		wantBool   *bool
+	}{
+		{name: "number value", event: ValueEvent{NumberValue{raw: "42"}}, wantInt: p(int64(42)), wantUint: p(uint64(42)), wantFloat: p(float64(42))},
+		{name: "string value", event: ValueEvent{StringValue{raw: `"hi"`}}, wantString: p("hi")},
+		{name: "boolean value", event: ValueEvent{BooleanValue{raw: "true"}}, wantBool: p(true)},
+		{name: "map key", event: MapKeyEvent{IdentifierValue{raw: "name"}}, wantString: p("name")},
+		{name: "list start", event: ListStartEvent{}},
+		{name: "map end", event: MapEndEvent{}},
+		{name: "error", event: ErrorEvent{Msg: "error"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testEventConversion(t, "ToStringEvent", tt.wantString, tt.event, ToStringEvent)
+			testEventConversion(t, "ToIntEvent", tt.wantInt, tt.event, ToIntEvent)
+			testEventConversion(t, "ToUintEvent", tt.wantUint, tt.event, ToUintEvent)
+			testEventConversion(t, "ToFloatEvent", tt.wantFloat, tt.event, ToFloatEvent)
+			testEventConversion(t, "ToBoolEvent", tt.wantBool, tt.event, ToBoolEvent)
+		})
+	}
+}
+
+// testEventConversion checks that conv returns want, or an error if want is nil.
+func testEventConversion[T comparable](t *testing.T, name string, want *T, e ParserEvent, conv func(ParserEvent) (T, error)) {
+	t.Helper()
+
+	got, err := conv(e)
+	if want == nil {
+		if err == nil {
+			t.Errorf("%s() = %v, want error", name, got)
+		}
+		return
+	}
+
+	if err != nil {
+		t.Errorf("%s() error = %v", name, err)
+	} else if got != *want {
+		t.Errorf("%s() = %v, want %v", name, got, *want)
+	}
+}
+
+func TestToBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    Value
+		expected []byte
+		wantErr  bool
+	}{
+		{"bytes", NewBytes([]byte{0, 0xff}), []byte{0, 0xff}, false},
+		{"base64 string", StringValue{raw: `"AP8="`}, []byte{0, 0xff}, false},
+		{"unpadded base64", IdentifierValue{"aGk"}, nil, true},
+		{"invalid base64", StringValue{raw: `"not base64"`}, nil, true},
+		{"number", NumberValue{"42"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToBytes(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ToBytes() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	if got := NewBytes([]byte{0, 0xff}).Raw(); got != `"AP8="` {
+		t.Errorf("BytesValue.Raw() = %q, want %q", got, `"AP8="`)
+	}
+
+	// Bytes are typed as the strings they are read back as.
+	var v Value = NewBytes([]byte{0, 0xff})
+	if got := v.Type(); got != StringValueType {
+		t.Errorf("BytesValue.Type() = %s, want %s", got, StringValueType)
+	}
+	if _, ok := As[BytesValue](v); !ok {
+		t.Errorf("As[BytesValue]() = false, want true")
+	}
+	if _, ok := As[BytesValue](StringValue{raw: `"AP8="`}); ok {
+		t.Errorf("As[BytesValue](string) = true, want false")
+	}
+}
+
+func TestToStringLenient(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{`port=8080`, "8080", false},
+		{`port=0x1F`, "0x1F", false},
+		{`port=-1.5`, "-1.5", false},
+		{`port="8080"`, "8080", false},
+		{`port=http`, "http", false},
+		{`port=true`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			m, err := ToMap(tt.input)
+			if err != nil {
+				t.Fatalf("ToMap() error = %v", err)
+			}
+
+			got, err := ToStringLenient(m["port"])
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToStringLenient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ToStringLenient() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := ToString(NumberValue{"8080"}); err == nil {
+		t.Errorf("ToString() accepted a number")
+	}
+}
+
+func TestToStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+		wantErr  bool
+	}{
+		{`v="x"`, []string{"x"}, false},
+		{`v=x`, []string{"x"}, false},
+		{`v=a;b`, []string{"a", "b"}, false},
+		{`v='a b';"c"`, []string{"a b", "c"}, false},
+		{`v=`, []string{}, false},
+		{`v=k:x`, nil, true},
+		{`v=a;1`, []string{"a", "1"}, false},
+		{`v=1`, []string{"1"}, false},
+		{`v=0x1F;true`, []string{"0x1F", "true"}, false},
+		{`v=nil`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			m, err := ToMap(tt.input)
+			if err != nil {
+				t.Fatalf("ToMap() error = %v", err)
+			}
+
+			got, err := ToStrings(m["v"])
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToStrings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ToStrings() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	if got, err := ToStrings(UndefinedValue{}); err != nil || !reflect.DeepEqual(got, []string{}) {
+		t.Errorf("ToStrings(undefined) = %q, %v, want empty list", got, err)
+	}
+}
+
+func TestToBoolLenient(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    Value
+		expected bool
+		wantErr  bool
+	}{
+		{"one", NumberValue{"1"}, true, false},
+		{"zero", NumberValue{"0"}, false, false},
+		{"true", BooleanValue{"true"}, true, false},
+		{"false", BooleanValue{"false"}, false, false},
+		{"two", NumberValue{"2"}, false, true},
+		{"float one", NumberValue{"1.0"}, false, true},
+		{"identifier", IdentifierValue{"yes"}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToBoolLenient(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToBoolLenient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ToBoolLenient() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := ToBool(NumberValue{"1"}); err == nil {
+		t.Errorf("ToBool() accepted a number")
+	}
+}
+
+func TestToPointer(t *testing.T) {
+	m, err := ToMap(`a=nil,b=,c="x",d=1`)
+	if err != nil {
+		t.Fatalf("ToMap() error = %v", err)
+	}
+
+	if got, err := ToPointer(m["a"], ToString); err != nil || got != nil {
+		t.Errorf("ToPointer(nil) = %v, %v, want nil pointer", got, err)
+	}
+	if got, err := ToPointer(m["b"], ToString); err != nil || got == nil || *got != "" {
+		t.Errorf("ToPointer(zero) = %v, %v, want pointer to empty string", got, err)
+	}
+	if got, err := ToPointer(m["c"], ToString); err != nil || got == nil || *got != "x" {
+		t.Errorf("ToPointer(string) = %v, %v, want pointer to %q", got, err, "x")
+	}
+	if got, err := ToPointer(m["d"], ToString); err == nil {
+		t.Errorf("ToPointer(number) = %v, want error", got)
+	}
+	if got, err := ToPointer(UndefinedValue{}, ToString); err != nil || got != nil {
+		t.Errorf("ToPointer(undefined) = %v, %v, want nil pointer", got, err)
+	}
+}
+
+func TestToFlagSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]bool
+		wantErr  bool
+	}{
+		{"prefixed flags", "features=^a;!b;^c", map[string]bool{"a": true, "b": false, "c": true}, false},
+		{"boolean pairs", "features=a:true;'b c':false;0x1:true", map[string]bool{"a": true, "b c": false, "1": true}, false},
+		{"non-boolean entry", "features=^a;b:1", nil, true},
+		{"not a map", "features=a;b", nil, true},
+		{"single value", "features=a", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ToMap(tt.input)
+			if err != nil {
+				t.Fatalf("ToMap() error = %v", err)
+			}
+
+			got, err := ToFlagSet(m["features"])
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToFlagSet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ToFlagSet() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestToURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   Value
+		want    string
+		wantErr bool
+	}{
+		{"string", StringValue{raw: `"https://example.com/v1"`}, "https://example.com/v1", false},
+		{"identifier", IdentifierValue{"localhost"}, "localhost", false},
+		{"malformed", StringValue{raw: `"http://%zz"`}, "", true},
+		{"number", NumberValue{"42"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToURL(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ToURL() expected error, got %v", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ToURL() error = %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("ToURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToMAC(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"quoted", `mac="00:11:22:33:44:55"`, "00:11:22:33:44:55", false},
+		{"hyphenated", `mac='00-1A-2B-3C-4D-5E'`, "00:1a:2b:3c:4d:5e", false},
+		{"invalid", `mac="00:11:22:33:44"`, "", true},
+		{"not a string", `mac=42`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ToMap(tt.input)
+			if err != nil {
+				t.Fatalf("ToMap() error = %v", err)
+			}
+
+			got, err := ToMAC(m["mac"])
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ToMAC() expected error, got %v", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ToMAC() error = %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("ToMAC() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		layout  string
+		want    time.Time
+		wantErr bool
+	}{
+		{"date", `created="2023-06-01"`, "2006-01-02", time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), false},
+		{"custom layout", `created="01/06/2023 14:30"`, "02/01/2006 15:04", time.Date(2023, 6, 1, 14, 30, 0, 0, time.UTC), false},
+		{"layout mismatch", `created="2023-06-01"`, "02/01/2006", time.Time{}, true},
+		{"not a string", `created=20230601`, "20060102", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ToMap(tt.input)
+			if err != nil {
+				t.Fatalf("ToMap() error = %v", err)
+			}
+
+			got, err := ToTime(m["created"], tt.layout)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ToTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToNumberWithUnit(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    Value
+		wantNum  float64
+		wantUnit string
+		wantErr  bool
+	}{
+		{"percent", StringValue{raw: `"75%"`}, 75, "%", false},
+		{"megabytes", StringValue{raw: `"512MB"`}, 512, "MB", false},
+		{"fraction", StringValue{raw: `"1.5 GiB"`}, 1.5, "GiB", false},
+		{"negative", StringValue{raw: `"-40C"`}, -40, "C", false},
+		{"exponent", StringValue{raw: `"1e3ms"`}, 1000, "ms", false},
+		{"unit starting with e", StringValue{raw: `"2em"`}, 2, "em", false},
+		{"no unit", StringValue{raw: `"42"`}, 42, "", false},
+		{"number", NumberValue{"0x10"}, 16, "", false},
+		{"no number", StringValue{raw: `"MB"`}, 0, "", true},
+		{"identifier", IdentifierValue{"px"}, 0, "", true},
+		{"boolean", BooleanValue{"true"}, 0, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			num, unit, err := ToNumberWithUnit(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ToNumberWithUnit() expected error, got %v %q", num, unit)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ToNumberWithUnit() error = %v", err)
+			}
+			if num != tt.wantNum || unit != tt.wantUnit {
+				t.Errorf("ToNumberWithUnit() = %v, %q, want %v, %q", num, unit, tt.wantNum, tt.wantUnit)
+			}
+		})
+	}
+}
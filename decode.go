@@ -0,0 +1,336 @@
+package kaval
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+)
+
+var (
+	// ErrUnexpectedEnd is returned when an event stream ends inside a value.
+	ErrUnexpectedEnd = errors.New("unexpected end of events")
+
+	// ErrMixedDocument is returned when a document with both ordered and
+	// labeled fields cannot be represented.
+	ErrMixedDocument = errors.New("document mixes ordered and labeled fields")
+)
+
+// document holds the decoded top-level sections of an input.
+type document struct {
+	ordered ListValue
+	labeled MapValue
+}
+
+// eventReader decodes values from a stream of parser events.
+type eventReader struct {
+	next func() (ParserEvent, bool)
+}
+
+// read returns the next event, turning an ErrorEvent into an error.
+func (r *eventReader) read() (ParserEvent, error) {
+	event, ok := r.next()
+	if !ok {
+		return nil, ErrUnexpectedEnd
+	}
+	if err, isError := event.(ErrorEvent); isError {
+		return nil, err
+	}
+	return event, nil
+}
+
+// value decodes the value starting with the given event.
+func (r *eventReader) value(event ParserEvent) (Value, error) {
+	switch e := event.(type) {
+	case ValueEvent:
+		return e.Value, nil
+	case ListStartEvent:
+		return r.list()
+	case MapStartEvent:
+		return r.dict()
+	default:
+		return nil, fmt.Errorf("unexpected event %T, expected value", event)
+	}
+}
+
+// list decodes the items of a list up to its ListEndEvent.
+func (r *eventReader) list() (ListValue, error) {
+	var list ListValue
+	for {
+		event, err := r.read()
+		if err != nil {
+			return ListValue{}, err
+		}
+		if _, isEnd := event.(ListEndEvent); isEnd {
+			return list, nil
+		}
+
+		item, err := r.value(event)
+		if err != nil {
+			return ListValue{}, err
+		}
+		list.items = append(list.items, item)
+	}
+}
+
+// dict decodes the entries of a map up to its MapEndEvent.
+func (r *eventReader) dict() (MapValue, error) {
+	var dict MapValue
+	for {
+		event, err := r.read()
+		if err != nil {
+			return MapValue{}, err
+		}
+		if _, isEnd := event.(MapEndEvent); isEnd {
+			return dict, nil
+		}
+
+		key, isKey := event.(MapKeyEvent)
+		if !isKey {
+			return MapValue{}, fmt.Errorf("unexpected event %T, expected map key", event)
+		}
+
+		if event, err = r.read(); err != nil {
+			return MapValue{}, err
+		}
+		value, err := r.value(event)
+		if err != nil {
+			return MapValue{}, err
+		}
+		dict.set(key.Value, value)
+	}
+}
+
+// decodeDocument decodes the ordered and labeled sections of an event stream.
+func decodeDocument(events iter.Seq[ParserEvent]) (doc document, err error) {
+	next, stop := iter.Pull(events)
+	defer stop()
+
+	r := &eventReader{next: next}
+	for {
+		event, ok := r.next()
+		if !ok {
+			return doc, nil
+		}
+
+		switch e := event.(type) {
+		case ErrorEvent:
+			return document{}, e
+		case ListStartEvent:
+			doc.ordered, err = r.list()
+		case MapStartEvent:
+			doc.labeled, err = r.dict()
+		default:
+			err = fmt.Errorf("unexpected top-level event %T", event)
+		}
+		if err != nil {
+			return document{}, err
+		}
+	}
+}
+
+// ParseAll parses the input and collects all events.
+//
+// Parsing stops at the first error which is returned as an ErrorEvent.
+func ParseAll(input string, opts ...ParseOptions) ([]ParserEvent, error) {
+	events := []ParserEvent{}
+	for event := range Parse(input, opts...) {
+		if err, isError := event.(ErrorEvent); isError {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// Validate parses the input and returns all errors found in it.
+//
+// Parsing recovers from each error at the next field separator, so errors
+// in multiple fields are reported at once. An empty slice is returned for
+// valid input.
+func Validate(input string, opts ...ParseOptions) []ErrorEvent {
+	opt := ParseDefaults()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	opt.recoverErrors = true
+
+	errs := []ErrorEvent{}
+	for event := range Parse(input, opt) {
+		if err, isError := event.(ErrorEvent); isError {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// IsValid reports whether the input parses without errors. It stops at the
+// first error.
+func IsValid(input string, opts ...ParseOptions) bool {
+	for event := range Parse(input, opts...) {
+		if _, isError := event.(ErrorEvent); isError {
+			return false
+		}
+	}
+	return true
+}
+
+// ToMap parses the input and returns its labeled fields by their name.
+//
+// Ordered values are not part of the result. Lists and maps are returned as
+// ListValue and MapValue respectively. Use MapValue.ToMap for the entries of
+// a map value by their key, which normalizes numeric keys to their canonical
+// decimal form.
+func ToMap(input string, opts ...ParseOptions) (map[string]Value, error) {
+	doc, err := decodeDocument(Parse(input, opts...))
+	if err != nil {
+		return nil, err
+	}
+	return doc.labeled.ToMap(), nil
+}
+
+// ToOrderedMap parses the input and returns its labeled fields in their
+// original order. Unlike ToMap, keys are kept as written in the input.
+func ToOrderedMap(input string, opts ...ParseOptions) (MapValue, error) {
+	doc, err := decodeDocument(Parse(input, opts...))
+	if err != nil {
+		return MapValue{}, err
+	}
+	return doc.labeled, nil
+}
+
+// Keys parses the input and returns the names of its labeled fields in their
+// original order, without decoding their values. Names are converted as the
+// keys of ToMap. An input of only ordered values has no keys and returns an
+// empty slice.
+func Keys(input string, opts ...ParseOptions) ([]string, error) {
+	keys := []string{}
+
+	depth := 0
+	for event := range Parse(input, opts...) {
+		switch e := event.(type) {
+		case ErrorEvent:
+			return nil, e
+		case ListStartEvent, MapStartEvent:
+			depth++
+		case ListEndEvent, MapEndEvent:
+			depth--
+		case MapKeyEvent:
+			if depth == 1 {
+				keys = append(keys, keyString(e.Value))
+			}
+		}
+	}
+	return keys, nil
+}
+
+// Decode parses the input and returns it as native Go data.
+//
+// Labeled fields are returned as a map[string]any and ordered values as a
+// []any, a single ordered value is returned by itself. Documents mixing both
+// are rejected with ErrMixedDocument. Values are converted by their type:
+//
+//   - nil and zero values to nil
+//   - booleans to bool
+//   - numbers to int64, uint64 if too large, or float64 otherwise
+//   - strings, identifiers and references to string
+//   - lists to []any and maps to map[string]any
+//
+// Map keys are converted as by MapValue.ToMap.
+func Decode(input string, opts ...ParseOptions) (any, error) {
+	doc, err := decodeDocument(Parse(input, opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case doc.ordered.Len() > 0 && doc.labeled.Len() > 0:
+		return nil, ErrMixedDocument
+	case doc.ordered.Len() == 1:
+		return nativeValue(doc.ordered.items[0])
+	case doc.ordered.Len() > 0:
+		return nativeValue(doc.ordered)
+	default:
+		return nativeValue(doc.labeled)
+	}
+}
+
+// nativeValue converts a Value to its native Go representation.
+func nativeValue(v Value) (any, error) {
+	switch val := v.(type) {
+	case NilValue, ZeroValue, UndefinedValue:
+		return nil, nil
+	case BooleanValue:
+		return val.ToBool()
+	case NumberValue:
+		if n, err := val.ToInt(); err == nil {
+			return n, nil
+		}
+		if n, err := val.ToUint(); err == nil {
+			return n, nil
+		}
+		return val.ToFloat()
+	case ListValue:
+		out := make([]any, len(val.items))
+		for i, item := range val.items {
+			n, err := nativeValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = n
+		}
+		return out, nil
+	case MapValue:
+		out := make(map[string]any, len(val.keys))
+		for i, key := range val.keys {
+			n, err := nativeValue(val.values[i])
+			if err != nil {
+				return nil, err
+			}
+			out[keyString(key)] = n
+		}
+		return out, nil
+	default:
+		if s, err := ToString(v); err == nil {
+			return s, nil
+		}
+		return v.Raw(), nil
+	}
+}
+
+// Flatten parses the input and returns every scalar value by its path.
+//
+// Paths join map keys with dots and append list indices in brackets, e.g.
+// "settings.theme" or "tags[0]". Ordered values are addressed by their
+// index alone, e.g. "[0]". Strings and identifiers are decoded, all other
+// values are returned in their raw form.
+func Flatten(input string, opts ...ParseOptions) (map[string]string, error) {
+	doc, err := decodeDocument(Parse(input, opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]string)
+	flattenValue(out, "", doc.ordered)
+	flattenValue(out, "", doc.labeled)
+	return out, nil
+}
+
+// flattenValue adds v and all values nested in it to out.
+func flattenValue(out map[string]string, path string, v Value) {
+	switch c := v.(type) {
+	case ListValue:
+		for i, item := range c.items {
+			flattenValue(out, fmt.Sprintf("%s[%d]", path, i), item)
+		}
+	case MapValue:
+		for i, key := range c.keys {
+			p := textOf(key)
+			if path != "" {
+				p = path + "." + p
+			}
+			flattenValue(out, p, c.values[i])
+		}
+	default:
+		out[path] = textOf(v)
+	}
+}
@@ -0,0 +1,407 @@
+package kaval
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []ParserEvent
+	}{
+		{"empty input", "", []ParserEvent{}},
+		{"spaces only", "   ", []ParserEvent{}},
+		{"newline and tab", "\n\t", []ParserEvent{}},
+		{"labeled field", "name=john", []ParserEvent{
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "name")},
+			ValueEvent{newValue(IdentifierValueType, "john")},
+			MapEndEvent{},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAll(tt.input)
+			if err != nil {
+				t.Fatalf("ParseAll() error = %v", err)
+			}
+
+			if got == nil {
+				t.Fatalf("ParseAll() = nil, want non-nil")
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseAll() = %#v, want %#v", got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		if _, err := ParseAll("a=1,,"); err == nil {
+			t.Errorf("ParseAll() expected error, got nil")
+		}
+	})
+}
+
+func TestToMap(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]Value
+	}{
+		{"empty input", "", map[string]Value{}},
+		{"spaces only", "   ", map[string]Value{}},
+		{"newline and tab", "\n\t", map[string]Value{}},
+		{"ordered values only", "a,b", map[string]Value{}},
+		{"complex example", "john, ^enabled, settings=theme:dark;fontSize:14, tags=dev;prod", map[string]Value{
+			"enabled": BooleanValue{"true"},
+			"settings": MapValue{
+				keys:   []Value{IdentifierValue{"theme"}, IdentifierValue{"fontSize"}},
+				values: []Value{IdentifierValue{"dark"}, NumberValue{"14"}},
+			},
+			"tags": ListValue{items: []Value{IdentifierValue{"dev"}, IdentifierValue{"prod"}}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToMap(tt.input)
+			if err != nil {
+				t.Fatalf("ToMap() error = %v", err)
+			}
+
+			if got == nil {
+				t.Fatalf("ToMap() = nil, want non-nil")
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ToMap() = %#v, want %#v", got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		if _, err := ToMap("settings=key:"); err == nil {
+			t.Errorf("ToMap() expected error, got nil")
+		}
+	})
+}
+
+func TestCompositeValues(t *testing.T) {
+	m, err := ToMap("settings=theme:dark;'font size':14, tags=dev;prod")
+	if err != nil {
+		t.Fatalf("ToMap() error = %v", err)
+	}
+
+	settings, ok := As[MapValue](m["settings"])
+	if !ok {
+		t.Fatalf("settings is %T, want MapValue", m["settings"])
+	}
+	if got := settings.Raw(); got != `theme:dark;"font size":14` {
+		t.Errorf("Raw() = %q, want %q", got, `theme:dark;"font size":14`)
+	}
+	if v, ok := settings.Get("font size"); !ok || v.Raw() != "14" {
+		t.Errorf("Get() = %v, %t, want 14", v, ok)
+	}
+	if _, ok := settings.Get("missing"); ok {
+		t.Errorf("Get() found missing key")
+	}
+
+	tags, ok := As[ListValue](m["tags"])
+	if !ok {
+		t.Fatalf("tags is %T, want ListValue", m["tags"])
+	}
+	if got := tags.String(); got != "dev;prod (list)" {
+		t.Errorf("String() = %q, want %q", got, "dev;prod (list)")
+	}
+	if tags.Len() != 2 || IsNil(tags) {
+		t.Errorf("Len() = %d, IsNil() = %t", tags.Len(), IsNil(tags))
+	}
+}
+
+func TestCompositeValues_Equal(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  string
+		equal bool
+	}{
+		{"same map", "m=a:1;b:2", "m=a:1;b:2", true},
+		{"reordered map", "m=a:1;b:2", "m=b:2;a:1", true},
+		{"different map value", "m=a:1;b:2", "m=a:1;b:3", false},
+		{"different map key", "m=a:1;b:2", "m=a:1;c:2", false},
+		{"map subset", "m=a:1;b:2", "m=a:1", false},
+		{"duplicate keys with different values", "m=k:1;k:1", "m=k:1;k:2", false},
+		{"duplicate keys reordered", "m=k:1;k:2", "m=k:2;k:1", true},
+		{"same list", "m=a;b", "m=a;b", true},
+		{"reordered list", "m=a;b", "m=b;a", false},
+		{"list and map", "m=a;b", "m=a:b", false},
+		{"string and identifier", `m=a`, `m="a"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ToMap(tt.a)
+			if err != nil {
+				t.Fatalf("ToMap(%q) error = %v", tt.a, err)
+			}
+			b, err := ToMap(tt.b)
+			if err != nil {
+				t.Fatalf("ToMap(%q) error = %v", tt.b, err)
+			}
+
+			if got := Equal(a["m"], b["m"]); got != tt.equal {
+				t.Errorf("Equal(%q, %q) = %t, want %t", a["m"], b["m"], got, tt.equal)
+			}
+			if got := Equal(b["m"], a["m"]); got != tt.equal {
+				t.Errorf("Equal(%q, %q) = %t, want %t", b["m"], a["m"], got, tt.equal)
+			}
+		})
+	}
+}
+
+func TestMapValue_Entries(t *testing.T) {
+	m, err := ToMap("m=b:2;a:1;c:3")
+	if err != nil {
+		t.Fatalf("ToMap() error = %v", err)
+	}
+
+	var got []string
+	for k, v := range m["m"].(MapValue).Entries() {
+		got = append(got, k.Raw()+"="+v.Raw())
+	}
+
+	if want := []string{"b=2", "a=1", "c=3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Entries() = %v, want %v", got, want)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]string
+	}{
+		{"empty input", "", map[string]string{}},
+		{"complex example", "^enabled, name=john, settings=theme:dark;fontSize:14;autoSave:true, tags=dev;prod", map[string]string{
+			"enabled":           "true",
+			"name":              "john",
+			"settings.theme":    "dark",
+			"settings.fontSize": "14",
+			"settings.autoSave": "true",
+			"tags[0]":           "dev",
+			"tags[1]":           "prod",
+		}},
+		{"ordered values", `a, "b c";d, x=nil`, map[string]string{
+			"[0]":    "a",
+			"[1][0]": "b c",
+			"[1][1]": "d",
+			"x":      "nil",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Flatten(tt.input)
+			if err != nil {
+				t.Fatalf("Flatten() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Flatten() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  *ParseOptions
+		expected []ErrorEvent
+	}{
+		{"empty input", "", nil, []ErrorEvent{}},
+		{"valid input", "^enabled, name=john, settings=theme:dark;fontSize:14, tags=dev;prod", nil, []ErrorEvent{}},
+		{"single error", "a=1,b==", nil, []ErrorEvent{
+			{Pos: Position{Offset: 6, Column: 7}, Msg: "expected value, got Assign"},
+		}},
+		{"multiple errors", ",a=1,b==,c=k:,^,d=2", nil, []ErrorEvent{
+			{Pos: Position{Offset: 0, Column: 1}, Msg: "expected identifier, or value, got FieldSeparator"},
+			{Pos: Position{Offset: 7, Column: 8}, Msg: "expected value, got Assign"},
+			{Pos: Position{Offset: 13, Column: 14}, Msg: "expected value, got FieldSeparator"},
+			{Pos: Position{Offset: 15, Column: 16}, Msg: "expected Identifier, got FieldSeparator"},
+		}},
+		{"lexer error stops validation", "a==,b=@,c==", nil, []ErrorEvent{
+			{Pos: Position{Offset: 2, Column: 3}, Msg: "expected value, got Assign"},
+			{Pos: Position{Offset: 6, Column: 7}, Msg: "unexpected character: U+0040 '@'"},
+		}},
+		{"ordered values disabled", "a,b=1", &ParseOptions{RejectOrdered: true}, []ErrorEvent{
+			{Pos: Position{Offset: 0, Column: 1}, Msg: "ordered value not allowed here"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []ParseOptions
+			if tt.options != nil {
+				opts = append(opts, *tt.options)
+			}
+
+			got := Validate(tt.input, opts...)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Validate() = %#v, want %#v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  *ParseOptions
+		expected bool
+	}{
+		{"empty input", "", nil, true},
+		{"complex example", "^enabled, name=john, settings=theme:dark;fontSize:14, tags=dev;prod", nil, true},
+		{"ordered values", "a, b, c=1", nil, true},
+		{"invalid value", "name==", nil, false},
+		{"lexer error", "a=@", nil, false},
+		{"ordered values disabled", "a, b, c=1", &ParseOptions{RejectOrdered: true}, false},
+		{"labeled fields with ordered values disabled", "c=1", &ParseOptions{RejectOrdered: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []ParseOptions
+			if tt.options != nil {
+				opts = append(opts, *tt.options)
+			}
+
+			if got := IsValid(tt.input, opts...); got != tt.expected {
+				t.Errorf("IsValid(%q) = %t, want %t", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+		wantErr  bool
+	}{
+		{"labeled fields", "name=x,age=30", []string{"name", "age"}, false},
+		{"nested keys are skipped", "^on,m=k:v;db:(host:x),tags=a;b", []string{"on", "m", "tags"}, false},
+		{"ordered values", "a,b", []string{}, false},
+		{"empty input", "", []string{}, false},
+		{"error", "name==", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Keys(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Keys() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Keys() = %#v, want %#v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNumericKeys(t *testing.T) {
+	const input = "m=0xFF:x;0o17:y;1_000:z;-0b1:w;1.50:v;name:n"
+
+	m, err := ToMap(input)
+	if err != nil {
+		t.Fatalf("ToMap() error = %v", err)
+	}
+	dict, ok := As[MapValue](m["m"])
+	if !ok {
+		t.Fatalf("ToMap()[m] = %#v, want MapValue", m["m"])
+	}
+
+	want := map[string]Value{
+		"255":  IdentifierValue{"x"},
+		"15":   IdentifierValue{"y"},
+		"1000": IdentifierValue{"z"},
+		"-1":   IdentifierValue{"w"},
+		"1.5":  IdentifierValue{"v"},
+		"name": IdentifierValue{"n"},
+	}
+	if got := dict.ToMap(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MapValue.ToMap() = %#v, want %#v", got, want)
+	}
+	if v, ok := dict.Get("255"); !ok || v.Raw() != "x" {
+		t.Errorf("Get(\"255\") = %v, %t, want x", v, ok)
+	}
+
+	ordered, err := ToOrderedMap(input)
+	if err != nil {
+		t.Fatalf("ToOrderedMap() error = %v", err)
+	}
+	field, ok := ordered.Get("m")
+	if !ok {
+		t.Fatalf("ToOrderedMap() has no field m")
+	}
+
+	var keys []string
+	for key := range field.(MapValue).Entries() {
+		keys = append(keys, key.Raw())
+	}
+	if want := []string{"0xFF", "0o17", "1_000", "-0b1", "1.50", "name"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("ToOrderedMap() keys = %q, want %q", keys, want)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected any
+		wantErr  error
+	}{
+		{"empty input", "", map[string]any{}, nil},
+		{"single scalar", "42", int64(42), nil},
+		{"single string", `"hello"`, "hello", nil},
+		{"single nil", "nil", nil, nil},
+		{"single list", "a;b", []any{"a", "b"}, nil},
+		{"ordered values", "1, 2.5, true", []any{int64(1), 2.5, true}, nil},
+		{"large number", "18446744073709551615", uint64(18446744073709551615), nil},
+		{"labeled fields", "^enabled, name='john', settings=theme:dark;0x10:nil, tags=dev;prod", map[string]any{
+			"enabled":  true,
+			"name":     "john",
+			"settings": map[string]any{"theme": "dark", "16": nil},
+			"tags":     []any{"dev", "prod"},
+		}, nil},
+		{"mixed document", "a, b=1", nil, ErrMixedDocument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decode() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Decode() = %#v, want %#v", got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		if _, err := Decode("settings=key:"); err == nil {
+			t.Errorf("Decode() expected error, got nil")
+		}
+	})
+
+	t.Run("undefined value", func(t *testing.T) {
+		if got, err := nativeValue(UndefinedValue{}); err != nil || got != nil {
+			t.Errorf("nativeValue() = %#v, %v, want nil", got, err)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package kaval
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrInputTooLarge is returned by LoadReader for inputs exceeding the size
+// limit.
+var ErrInputTooLarge = errors.New("input too large")
+
+// Document provides typed access to the labeled fields of a parsed input.
+type Document struct {
+	fields MapValue
+}
+
+// Load parses the input into a Document.
+//
+// Ordered values are not accessible through the Document.
+func Load(input string, opts ...ParseOptions) (*Document, error) {
+	doc, err := decodeDocument(Parse(input, opts...))
+	if err != nil {
+		return nil, err
+	}
+	return &Document{fields: doc.labeled}, nil
+}
+
+// LoadReader reads the input from r and parses it into a Document like Load.
+// It fails with ErrInputTooLarge if r holds more than maxSize bytes, a
+// maxSize of zero or less means no limit.
+func LoadReader(r io.Reader, maxSize int64, opts ...ParseOptions) (*Document, error) {
+	if maxSize > 0 {
+		// Read one more byte to detect input exceeding the limit.
+		r = io.LimitReader(r, maxSize+1)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrInputTooLarge, maxSize)
+	}
+	return Load(string(data), opts...)
+}
+
+// Get returns the value of the field with the given key. A missing key
+// returns UndefinedValue, unlike name= which returns ZeroValue.
+func (d *Document) Get(key string) (Value, bool) {
+	if v, ok := d.fields.Get(key); ok {
+		return v, true
+	}
+	return UndefinedValue{}, false
+}
+
+// GetString returns the value of the field with the given key as a string.
+func (d *Document) GetString(key string) (string, bool) {
+	return getAs(d, key, ToString)
+}
+
+// GetInt returns the value of the field with the given key as an int64.
+func (d *Document) GetInt(key string) (int64, bool) {
+	return getAs(d, key, ToInt)
+}
+
+// GetBool returns the value of the field with the given key as a boolean.
+func (d *Document) GetBool(key string) (bool, bool) {
+	return getAs(d, key, ToBool)
+}
+
+// GetList returns the items of the list field with the given key.
+func (d *Document) GetList(key string) ([]Value, bool) {
+	v, ok := d.Get(key)
+	if !ok {
+		return nil, false
+	}
+	list, ok := As[ListValue](v)
+	if !ok {
+		return nil, false
+	}
+	return list.Items(), true
+}
+
+// GetDict returns the map field with the given key as a nested Document.
+func (d *Document) GetDict(key string) (*Document, bool) {
+	v, ok := d.Get(key)
+	if !ok {
+		return nil, false
+	}
+	dict, ok := As[MapValue](v)
+	if !ok {
+		return nil, false
+	}
+	return &Document{fields: dict}, true
+}
+
+// getAs looks up the field with the given key and converts it with fn.
+func getAs[T any](d *Document, key string, fn func(Value) (T, error)) (T, bool) {
+	var zero T
+
+	v, ok := d.Get(key)
+	if !ok {
+		return zero, false
+	}
+	out, err := fn(v)
+	if err != nil {
+		return zero, false
+	}
+	return out, true
+}
@@ -0,0 +1,147 @@
+package kaval
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDocument(t *testing.T) {
+	doc, err := Load("^enabled, name=john, settings=theme:dark;fontSize:14;autoSave:true, tags=dev;prod")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	t.Run("GetString", func(t *testing.T) {
+		if got, ok := doc.GetString("name"); !ok || got != "john" {
+			t.Errorf("GetString() = %q, %t, want %q", got, ok, "john")
+		}
+		if _, ok := doc.GetString("missing"); ok {
+			t.Errorf("GetString() found missing key")
+		}
+	})
+
+	t.Run("GetBool", func(t *testing.T) {
+		if got, ok := doc.GetBool("enabled"); !ok || !got {
+			t.Errorf("GetBool() = %t, %t, want true", got, ok)
+		}
+		if _, ok := doc.GetBool("name"); ok {
+			t.Errorf("GetBool() converted a non-boolean")
+		}
+	})
+
+	t.Run("GetList", func(t *testing.T) {
+		got, ok := doc.GetList("tags")
+		if !ok {
+			t.Fatalf("GetList() not found")
+		}
+		want := []Value{IdentifierValue{"dev"}, IdentifierValue{"prod"}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GetList() = %v, want %v", got, want)
+		}
+		if _, ok := doc.GetList("name"); ok {
+			t.Errorf("GetList() returned a scalar")
+		}
+	})
+
+	t.Run("GetDict", func(t *testing.T) {
+		settings, ok := doc.GetDict("settings")
+		if !ok {
+			t.Fatalf("GetDict() not found")
+		}
+		if got, ok := settings.GetString("theme"); !ok || got != "dark" {
+			t.Errorf("GetString() = %q, %t, want %q", got, ok, "dark")
+		}
+		if got, ok := settings.GetInt("fontSize"); !ok || got != 14 {
+			t.Errorf("GetInt() = %d, %t, want %d", got, ok, 14)
+		}
+		if got, ok := settings.GetBool("autoSave"); !ok || !got {
+			t.Errorf("GetBool() = %t, %t, want true", got, ok)
+		}
+		if _, ok := doc.GetDict("tags"); ok {
+			t.Errorf("GetDict() returned a list")
+		}
+	})
+
+	t.Run("GetInt", func(t *testing.T) {
+		if _, ok := doc.GetInt("name"); ok {
+			t.Errorf("GetInt() converted a non-number")
+		}
+	})
+}
+
+func TestLoad_Error(t *testing.T) {
+	if _, err := Load("a=1,,"); err == nil {
+		t.Errorf("Load() expected error, got nil")
+	}
+}
+
+func TestDocument_Get(t *testing.T) {
+	doc, err := Load("name=john, empty=")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		key      string
+		wantType ValueType
+		wantOK   bool
+		wantZero bool
+	}{
+		{"name", IdentifierValueType, true, false},
+		{"empty", ZeroValueType, true, true},
+		{"missing", UndefinedValueType, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, ok := doc.Get(tt.key)
+			if ok != tt.wantOK {
+				t.Errorf("Get() ok = %t, want %t", ok, tt.wantOK)
+			}
+			if got.Type() != tt.wantType {
+				t.Errorf("Get() type = %v, want %v", got.Type(), tt.wantType)
+			}
+			if IsZero(got) != tt.wantZero || IsNil(got) != tt.wantZero {
+				t.Errorf("IsZero() = %t, IsNil() = %t, want %t", IsZero(got), IsNil(got), tt.wantZero)
+			}
+		})
+	}
+}
+
+func TestLoadReader(t *testing.T) {
+	input := "name=john, tags=dev;prod"
+
+	for _, maxSize := range []int64{0, int64(len(input))} {
+		doc, err := LoadReader(strings.NewReader(input), maxSize)
+		if err != nil {
+			t.Fatalf("LoadReader(%d) error = %v", maxSize, err)
+		}
+		if got, ok := doc.GetString("name"); !ok || got != "john" {
+			t.Errorf("GetString() = %q, %t, want %q", got, ok, "john")
+		}
+	}
+
+	t.Run("too large", func(t *testing.T) {
+		_, err := LoadReader(strings.NewReader(input), int64(len(input)-1))
+		if !errors.Is(err, ErrInputTooLarge) {
+			t.Errorf("LoadReader() error = %v, want %v", err, ErrInputTooLarge)
+		}
+	})
+
+	t.Run("parse error", func(t *testing.T) {
+		if _, err := LoadReader(strings.NewReader("a=="), 0); err == nil {
+			t.Errorf("LoadReader() expected error, got nil")
+		}
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		r := io.MultiReader(strings.NewReader("a=1"), iotest.ErrReader(io.ErrUnexpectedEOF))
+		if _, err := LoadReader(r, 0); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("LoadReader() error = %v, want %v", err, io.ErrUnexpectedEOF)
+		}
+	})
+}
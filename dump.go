@@ -0,0 +1,86 @@
+package kaval
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DumpEvents parses the input and returns its events with one event per
+// line, e.g. `MapKey identifier "name"` or `Value number "30"`.
+//
+// The format is flat and deterministic to allow diffing the event streams
+// of inputs, for example in golden files. Values are written as their
+// type followed by their quoted raw text.
+func DumpEvents(input string, opts ...ParseOptions) (string, error) {
+	events, err := ParseAll(input, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, event := range events {
+		sb.WriteString(dumpEvent(event))
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
+
+// dumpEvent returns the line of a single event.
+func dumpEvent(event ParserEvent) string {
+	switch e := event.(type) {
+	case ListStartEvent:
+		return "ListStart"
+	case ListEndEvent:
+		return "ListEnd"
+	case MapStartEvent:
+		return "MapStart"
+	case MapEndEvent:
+		return "MapEnd"
+	case MapKeyEvent:
+		return fmt.Sprintf("MapKey %s %q", e.Type(), e.Raw())
+	case ValueEvent:
+		return fmt.Sprintf("Value %s %q", e.Type(), e.Raw())
+	default:
+		return fmt.Sprintf("%T", event)
+	}
+}
+
+// TokenInfo pairs a token with the Value it becomes when parsed.
+type TokenInfo struct {
+	Token
+
+	// Value is the Value of a value token and nil for all other tokens.
+	Value Value
+
+	// DecodedString is the text of the Value with strings unquoted and
+	// numbers in decimal form, e.g. hi for "hi" and 255 for 0xFF.
+	DecodedString string
+}
+
+// Tokens lexes the input and returns every token with its Value, including
+// the final EOF or error token.
+func Tokens(input string, opts ...LexOptions) []TokenInfo {
+	var infos []TokenInfo
+	for tok := range Lex(input, opts...) {
+		info := TokenInfo{Token: tok, Value: valueFromToken(tok)}
+		if info.Value != nil {
+			info.DecodedString = keyString(info.Value)
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// CountTokens lexes the input and returns the number of tokens including the
+// final EOF, e.g. to size buffers before parsing. It returns the tokens
+// counted so far and an error if the input does not lex.
+func CountTokens(input string, opts ...LexOptions) (int, error) {
+	var n int
+	for tok := range Lex(input, opts...) {
+		if tok.Typ == TokenError {
+			return n, fmt.Errorf("error at %s: %s", tok.Pos, tok.Val)
+		}
+		n++
+	}
+	return n, nil
+}
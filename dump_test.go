@@ -0,0 +1,114 @@
+package kaval
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestDumpEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"empty input", "", "", false},
+		{"multiple fields", "name=john, age=30, active=true", `MapStart
+MapKey identifier "name"
+Value identifier "john"
+MapKey identifier "age"
+Value number "30"
+MapKey identifier "active"
+Value boolean "true"
+MapEnd
+`, false},
+		{"nested values", `a, b="x y";nil, c=k:0x1F`, `ListStart
+Value identifier "a"
+ListEnd
+MapStart
+MapKey identifier "b"
+ListStart
+Value string "\"x y\""
+Value nil "nil"
+ListEnd
+MapKey identifier "c"
+MapStart
+MapKey identifier "k"
+Value number "0x1F"
+MapEnd
+MapEnd
+`, false},
+		{"error", "a==", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DumpEvents(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DumpEvents() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("DumpEvents() =\n%s\nwant:\n%s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTokens(t *testing.T) {
+	got := Tokens(`a="hi",b=0xFF`)
+
+	expected := []TokenInfo{
+		{Token{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, EndPos: Position{Offset: 1, Column: 2}, Val: "a"}, IdentifierValue{raw: "a"}, "a"},
+		{Token{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, EndPos: Position{Offset: 2, Column: 3}, Val: "="}, nil, ""},
+		{Token{Typ: TokenString, Pos: Position{Offset: 2, Column: 3}, EndPos: Position{Offset: 6, Column: 7}, Val: `"hi"`}, StringValue{raw: `"hi"`}, "hi"},
+		{Token{Typ: TokenFieldSeparator, Pos: Position{Offset: 6, Column: 7}, EndPos: Position{Offset: 7, Column: 8}, Val: ","}, nil, ""},
+		{Token{Typ: TokenIdentifier, Pos: Position{Offset: 7, Column: 8}, EndPos: Position{Offset: 8, Column: 9}, Val: "b"}, IdentifierValue{raw: "b"}, "b"},
+		{Token{Typ: TokenAssign, Pos: Position{Offset: 8, Column: 9}, EndPos: Position{Offset: 9, Column: 10}, Val: "="}, nil, ""},
+		{Token{Typ: TokenNumber, Pos: Position{Offset: 9, Column: 10}, EndPos: Position{Offset: 13, Column: 14}, Val: "0xFF"}, NumberValue{raw: "0xFF"}, "255"},
+		{Token{Typ: TokenEOF, Pos: Position{Offset: 13, Column: 14}, EndPos: Position{Offset: 13, Column: 14}}, nil, ""},
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Tokens() = %v, want %v", got, expected)
+	}
+}
+
+func TestTokens_Error(t *testing.T) {
+	got := Tokens("a=?")
+	if n := len(got); n != 3 || got[n-1].Typ != TokenError || got[n-1].Value != nil {
+		t.Errorf("Tokens() = %v, want a final error token without value", got)
+	}
+}
+
+func TestCountTokens(t *testing.T) {
+	inputs := []string{
+		"",
+		"a",
+		`a="hi",b=0xFF`,
+		"tags=a;b, m=k:v, ^on, !off",
+		"s=db:(host:x;port:1)",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			got, err := CountTokens(input)
+			if err != nil {
+				t.Fatalf("CountTokens() error = %v", err)
+			}
+			if want := len(slices.Collect(Lex(input))); got != want {
+				t.Errorf("CountTokens() = %d, want %d", got, want)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		got, err := CountTokens("a=?")
+		if err == nil {
+			t.Fatalf("CountTokens() expected error, got nil")
+		}
+		if got != 2 {
+			t.Errorf("CountTokens() = %d, want 2 tokens before the error", got)
+		}
+	})
+}
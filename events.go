@@ -0,0 +1,153 @@
+package kaval
+
+import (
+	"fmt"
+	"iter"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// ValueEvents walks a Go value and yields the parser events of its
+// plainfields representation, e.g. to be encoded with Encode.
+//
+// Maps become labeled fields and slices become ordered fields, a single
+// scalar becomes a document with one ordered value. Map keys are sorted so
+// the events are deterministic. Values that cannot be represented, such as
+// NaN, are reported as an ErrorEvent.
+func ValueEvents(v any) iter.Seq[ParserEvent] {
+	return func(yield func(ParserEvent) bool) {
+		val, err := goValue(v)
+		if err != nil {
+			yield(ErrorEvent{Msg: err.Error()})
+			return
+		}
+
+		switch val.(type) {
+		case ListValue, MapValue:
+			valueEvents(val, yield)
+		default:
+			_ = yield(ListStartEvent{}) && yield(ValueEvent{val}) && yield(ListEndEvent{})
+		}
+	}
+}
+
+// valueEvents yields the events of v. It reports false if yield stopped.
+func valueEvents(v Value, yield func(ParserEvent) bool) bool {
+	switch val := v.(type) {
+	case ListValue:
+		if !yield(ListStartEvent{}) {
+			return false
+		}
+		for _, item := range val.items {
+			if !valueEvents(item, yield) {
+				return false
+			}
+		}
+		return yield(ListEndEvent{})
+	case MapValue:
+		if !yield(MapStartEvent{}) {
+			return false
+		}
+		for i, key := range val.keys {
+			if !yield(MapKeyEvent{key}) || !valueEvents(val.values[i], yield) {
+				return false
+			}
+		}
+		return yield(MapEndEvent{})
+	default:
+		return yield(ValueEvent{v})
+	}
+}
+
+// goValue converts a Go value to a Value.
+func goValue(v any) (Value, error) {
+	switch val := v.(type) {
+	case Value:
+		return val, nil
+	case nil:
+		return NilValue{}, nil
+	case bool:
+		return NewBoolean(val), nil
+	case string:
+		return textValue(val), nil
+	case int:
+		return NumberValue{raw: strconv.FormatInt(int64(val), 10)}, nil
+	case int8:
+		return NumberValue{raw: strconv.FormatInt(int64(val), 10)}, nil
+	case int16:
+		return NumberValue{raw: strconv.FormatInt(int64(val), 10)}, nil
+	case int32:
+		return NumberValue{raw: strconv.FormatInt(int64(val), 10)}, nil
+	case int64:
+		return NumberValue{raw: strconv.FormatInt(val, 10)}, nil
+	case uint:
+		return NumberValue{raw: strconv.FormatUint(uint64(val), 10)}, nil
+	case uint8:
+		return NumberValue{raw: strconv.FormatUint(uint64(val), 10)}, nil
+	case uint16:
+		return NumberValue{raw: strconv.FormatUint(uint64(val), 10)}, nil
+	case uint32:
+		return NumberValue{raw: strconv.FormatUint(uint64(val), 10)}, nil
+	case uint64:
+		return NumberValue{raw: strconv.FormatUint(val, 10)}, nil
+	case float32:
+		return floatValue(float64(val), 32)
+	case float64:
+		return floatValue(val, 64)
+	case []any:
+		list := ListValue{items: make([]Value, len(val))}
+		for i, item := range val {
+			conv, err := goValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list.items[i] = conv
+		}
+		return list, nil
+	case []string:
+		list := ListValue{items: make([]Value, len(val))}
+		for i, item := range val {
+			list.items[i] = textValue(item)
+		}
+		return list, nil
+	case map[string]any:
+		var dict MapValue
+		for _, key := range slices.Sorted(maps.Keys(val)) {
+			conv, err := goValue(val[key])
+			if err != nil {
+				return nil, err
+			}
+			dict.set(textValue(key), conv)
+		}
+		return dict, nil
+	case map[string]string:
+		var dict MapValue
+		for _, key := range slices.Sorted(maps.Keys(val)) {
+			dict.set(textValue(key), textValue(val[key]))
+		}
+		return dict, nil
+	case fmt.Stringer:
+		return textValue(val.String()), nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+// textValue returns s as an identifier if it reads back as one, and as a
+// string otherwise.
+func textValue(s string) Value {
+	if id, err := NewIdentifier(s); err == nil {
+		return id
+	}
+	return NewString(s)
+}
+
+// floatValue returns f as a number with the given bit size.
+func floatValue(f float64, bitSize int) (Value, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("unsupported number %v", f)
+	}
+	return NumberValue{raw: strconv.FormatFloat(f, 'g', -1, bitSize)}, nil
+}
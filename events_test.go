@@ -0,0 +1,97 @@
+package kaval
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestValueEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    any
+		expected []ParserEvent
+	}{
+		{"map", map[string]any{"a": 1}, []ParserEvent{
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "a")},
+			ValueEvent{newValue(NumberValueType, "1")},
+			MapEndEvent{},
+		}},
+
+		{"sorted keys", map[string]string{"b": "x", "a": "y"}, []ParserEvent{
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "a")},
+			ValueEvent{newValue(IdentifierValueType, "y")},
+			MapKeyEvent{newValue(IdentifierValueType, "b")},
+			ValueEvent{newValue(IdentifierValueType, "x")},
+			MapEndEvent{},
+		}},
+
+		{"nested", map[string]any{"l": []any{true, nil}, "m": map[string]any{"k": 1.5}}, []ParserEvent{
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "l")},
+			ListStartEvent{},
+			ValueEvent{newValue(BooleanValueType, "true")},
+			ValueEvent{newValue(NilValueType, "nil")},
+			ListEndEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "m")},
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "k")},
+			ValueEvent{newValue(NumberValueType, "1.5")},
+			MapEndEvent{},
+			MapEndEvent{},
+		}},
+
+		{"slice", []string{"a", "hello world", "true"}, []ParserEvent{
+			ListStartEvent{},
+			ValueEvent{newValue(IdentifierValueType, "a")},
+			ValueEvent{newValue(StringValueType, `"hello world"`)},
+			ValueEvent{newValue(StringValueType, `"true"`)},
+			ListEndEvent{},
+		}},
+
+		{"scalar", int8(-3), []ParserEvent{
+			ListStartEvent{},
+			ValueEvent{newValue(NumberValueType, "-3")},
+			ListEndEvent{},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := slices.Collect(ValueEvents(tt.input))
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("ValueEvents() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValueEvents_Errors(t *testing.T) {
+	for _, input := range []any{struct{}{}, []any{1, func() {}}, math.NaN()} {
+		got := slices.Collect(ValueEvents(input))
+		if len(got) != 1 {
+			t.Fatalf("ValueEvents(%T) = %v, want a single error", input, got)
+		}
+		var errEvent ErrorEvent
+		if !errors.As(got[0].(error), &errEvent) {
+			t.Errorf("ValueEvents(%T) = %v, want ErrorEvent", input, got[0])
+		}
+	}
+}
+
+func TestValueEvents_Encode(t *testing.T) {
+	got, err := Encode(ValueEvents(map[string]any{
+		"name": "john doe",
+		"tags": []any{"a", "b"},
+		"age":  42,
+	}))
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if want := `age=42,name="john doe",tags=a;b`; got != want {
+		t.Errorf("Encode() = %q, want %q", got, want)
+	}
+}
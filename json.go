@@ -0,0 +1,156 @@
+package kaval
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+func (v NilValue) MarshalJSON() ([]byte, error)        { return []byte("null"), nil }
+func (v ZeroValue) MarshalJSON() ([]byte, error)       { return []byte("null"), nil }
+func (v UndefinedValue) MarshalJSON() ([]byte, error)  { return []byte("null"), nil }
+func (v BooleanValue) MarshalJSON() ([]byte, error)    { return []byte(v.raw), nil }
+func (v IdentifierValue) MarshalJSON() ([]byte, error) { return json.Marshal(v.raw) }
+func (v ReferenceValue) MarshalJSON() ([]byte, error)  { return json.Marshal(v.raw) }
+func (v BytesValue) MarshalJSON() ([]byte, error)      { return json.Marshal(v.data) }
+
+func (v NumberValue) MarshalJSON() ([]byte, error) {
+	if n, err := v.ToInt(); err == nil {
+		return strconv.AppendInt(nil, n, 10), nil
+	}
+	if n, err := v.ToUint(); err == nil {
+		return strconv.AppendUint(nil, n, 10), nil
+	}
+	f, err := v.ToFloat()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(f)
+}
+
+func (v StringValue) MarshalJSON() ([]byte, error) {
+	s, err := v.ToString()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+func (v ListValue) MarshalJSON() ([]byte, error) {
+	return marshalArray(v.items, func(v Value) any { return v })
+}
+
+func (v MapValue) MarshalJSON() ([]byte, error) {
+	return marshalObject(v, func(v Value) any { return v })
+}
+
+// typedValue marshals a Value as a JSON object tagged with its type.
+type typedValue struct {
+	Value
+}
+
+func (v typedValue) MarshalJSON() ([]byte, error) {
+	var inner any = v.Value
+	switch c := v.Value.(type) {
+	case ListValue:
+		b, err := marshalArray(c.items, wrapTyped)
+		if err != nil {
+			return nil, err
+		}
+		inner = json.RawMessage(b)
+	case MapValue:
+		b, err := marshalObject(c, wrapTyped)
+		if err != nil {
+			return nil, err
+		}
+		inner = json.RawMessage(b)
+	}
+
+	return json.Marshal(struct {
+		Type  string `json:"type"`
+		Value any    `json:"value"`
+	}{v.Type().String(), inner})
+}
+
+// wrapTyped wraps a Value to be marshaled with its type tag.
+func wrapTyped(v Value) any {
+	return typedValue{v}
+}
+
+// marshalArray marshals items as a JSON array, wrapping each with wrap.
+func marshalArray(items []Value, wrap func(Value) any) ([]byte, error) {
+	out := make([]any, len(items))
+	for i, item := range items {
+		out[i] = wrap(item)
+	}
+	return json.Marshal(out)
+}
+
+// marshalObject marshals a MapValue as a JSON object preserving the order
+// of its entries, wrapping each value with wrap.
+func marshalObject(m MapValue, wrap func(Value) any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, err := json.Marshal(textOf(key))
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+
+		v, err := json.Marshal(wrap(m.values[i]))
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// documentJSON marshals a parsed input as a JSON object of its labeled
+// fields, or as a JSON array if it only contains ordered values.
+func documentJSON(input string, opts []ParseOptions, wrap func(Value) any) ([]byte, error) {
+	doc, err := decodeDocument(Parse(input, opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case doc.ordered.Len() > 0 && doc.labeled.Len() > 0:
+		return nil, ErrMixedDocument
+	case doc.ordered.Len() > 0:
+		return marshalArray(doc.ordered.items, wrap)
+	default:
+		return marshalObject(doc.labeled, wrap)
+	}
+}
+
+// ToJSON converts the input to JSON.
+//
+// Labeled fields become a JSON object, a document of only ordered values
+// becomes a JSON array. Documents mixing both are rejected with
+// ErrMixedDocument.
+func ToJSON(input string, opts ...ParseOptions) ([]byte, error) {
+	return documentJSON(input, opts, func(v Value) any { return v })
+}
+
+// ToJSONTyped converts the input to JSON like ToJSON, but represents every
+// value as an object of the form {"type":"number","value":42}.
+//
+// This preserves distinctions JSON cannot express otherwise, such as
+// identifiers versus strings.
+func ToJSONTyped(input string, opts ...ParseOptions) ([]byte, error) {
+	return documentJSON(input, opts, wrapTyped)
+}
+
+// ToRawJSON returns the JSON encoding of a single value, e.g. to embed it in
+// a larger JSON document. A nil Value is encoded as null.
+func ToRawJSON(v Value) (json.RawMessage, error) {
+	return json.Marshal(v)
+}
@@ -0,0 +1,104 @@
+package kaval
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty input", "", `{}`},
+		{"labeled fields", `^enabled, name=john, quoted="a\tb", age=30, ratio=1.5, hex=0xFF, none=nil`,
+			`{"enabled":true,"name":"john","quoted":"a\tb","age":30,"ratio":1.5,"hex":255,"none":null}`},
+		{"composites", "tags=dev;prod, settings=theme:dark;size:14",
+			`{"tags":["dev","prod"],"settings":{"theme":"dark","size":14}}`},
+		{"ordered values", "a,1,true", `["a",1,true]`},
+		{"large unsigned", "n=18446744073709551615", `{"n":18446744073709551615}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToJSON(tt.input)
+			if err != nil {
+				t.Fatalf("ToJSON() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ToJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToJSONTyped(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"identifier", "x=foo", `{"x":{"type":"identifier","value":"foo"}}`},
+		{"string", `x="foo"`, `{"x":{"type":"string","value":"foo"}}`},
+		{"number", "x=42", `{"x":{"type":"number","value":42}}`},
+		{"list", `x=foo;"foo"`,
+			`{"x":{"type":"list","value":[{"type":"identifier","value":"foo"},{"type":"string","value":"foo"}]}}`},
+		{"map", "x=k:nil",
+			`{"x":{"type":"map","value":{"k":{"type":"nil","value":null}}}}`},
+		{"ordered values", "true", `[{"type":"boolean","value":true}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToJSONTyped(tt.input)
+			if err != nil {
+				t.Fatalf("ToJSONTyped() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ToJSONTyped() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToJSON_Errors(t *testing.T) {
+	if _, err := ToJSON("a,b=1"); !errors.Is(err, ErrMixedDocument) {
+		t.Errorf("ToJSON() error = %v, want %v", err, ErrMixedDocument)
+	}
+	if _, err := ToJSON("a=1,,"); err == nil {
+		t.Errorf("ToJSON() expected error, got nil")
+	}
+}
+
+func TestToRawJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		value Value
+		want  string
+	}{
+		{"number", NumberValue{"0xFF"}, `255`},
+		{"string", StringValue{raw: `"a\tb"`}, `"a\tb"`},
+		{"identifier", IdentifierValue{"john"}, `"john"`},
+		{"list", ListValue{[]Value{IdentifierValue{"a"}, NumberValue{"1"}, BooleanValue{"true"}}}, `["a",1,true]`},
+		{"nil", nil, `null`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToRawJSON(tt.value)
+			if err != nil {
+				t.Fatalf("ToRawJSON() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ToRawJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		if _, err := ToRawJSON(StringValue{raw: `"\q"`}); err == nil {
+			t.Errorf("ToRawJSON() expected error, got nil")
+		}
+	})
+}
@@ -1,19 +1,83 @@
 package kaval
 
 import (
+	"cmp"
 	"fmt"
 	"iter"
+	"strings"
 	"unicode/utf8"
 )
 
-const eof = -1
+const (
+	eof     = -1 // End of the input.
+	badRune = -2 // Byte that is not valid UTF-8.
+)
+
+// LexOptions holds options for lexing.
+type LexOptions struct {
+	// CaseInsensitiveKeywords recognizes the keywords true, false and nil
+	// regardless of their case, e.g. TRUE or Nil.
+	CaseInsensitiveKeywords bool
+
+	// ReferencePrefix enables references to other values, e.g. @HOME with
+	// the prefix set to '@'. The prefix must be a character that has no
+	// other meaning to the lexer. References are disabled if it is zero.
+	ReferencePrefix rune
+
+	// FieldSeparator, ListSeparator and PairSeparator replace the default
+	// separators `,`, `;` and `:` with strings of one or more characters,
+	// e.g. `::` as the pair separator. Empty strings keep the defaults. The
+	// separators must not start with characters that are valid in values.
+	FieldSeparator string
+	ListSeparator  string
+	PairSeparator  string
+
+	// MaxNumberLen limits the length in bytes of number literals, longer
+	// numbers are reported as errors. Zero means no limit.
+	MaxNumberLen int
+
+	// GreedyValues reads the rest of a field after its assignment as a
+	// single value, up to the next unescaped field separator or comment,
+	// e.g. q=a=b assigns a=b to q. Values that are a single token, such as
+	// numbers, strings and keywords, keep their type. Any other value is
+	// read as an identifier including embedded `=`, `:` and `;`, so lists
+	// and maps cannot be assigned.
+	GreedyValues bool
+}
+
+// LexDefaults returns the default lexing options.
+func LexDefaults() LexOptions {
+	return LexOptions{}
+}
+
+// separator returns the text of the separator with the given token type.
+func (opt LexOptions) separator(typ TokenType) string {
+	switch typ {
+	case TokenFieldSeparator:
+		return cmp.Or(opt.FieldSeparator, ",")
+	case TokenListSeparator:
+		return cmp.Or(opt.ListSeparator, ";")
+	case TokenPairSeparator:
+		return cmp.Or(opt.PairSeparator, ":")
+	default:
+		return ""
+	}
+}
+
+// hasCustomSeparators reports whether any of the separators is configured.
+func (opt LexOptions) hasCustomSeparators() bool {
+	return opt.FieldSeparator != "" || opt.ListSeparator != "" || opt.PairSeparator != ""
+}
 
 // stateFn represents the state of the scanner as a function that returns the advance state.
 type stateFn func(*lexer) stateFn
 
 // lexer holds the state of our scanner.
 type lexer struct {
-	input string // The string being scanned.
+	input   string     // The string being scanned.
+	options LexOptions // Options controlling the scanner.
+
+	customSeparators bool // Set if separators are matched by acceptSeparator.
 
 	yield func(Token) bool // Yield callback.
 	done  bool             // Set to true if yield returns false.
@@ -41,6 +105,9 @@ func (l *lexer) next() rune {
 	// Update column.
 	l.pos.Column++
 
+	if r == utf8.RuneError && w == 1 {
+		return badRune
+	}
 	return r
 }
 
@@ -50,9 +117,20 @@ func (l *lexer) undo() {
 }
 
 // peek returns but does not consume the next rune.
+//
+// It decodes the rune in place rather than calling next and undo, as the
+// number states peek at every digit.
 func (l *lexer) peek() rune {
-	r := l.next()
-	l.undo()
+	if l.pos.Offset >= len(l.input) {
+		return eof
+	}
+	if c := l.input[l.pos.Offset]; c < utf8.RuneSelf {
+		return rune(c)
+	}
+	r, w := utf8.DecodeRuneInString(l.input[l.pos.Offset:])
+	if r == utf8.RuneError && w == 1 {
+		return badRune
+	}
 	return r
 }
 
@@ -66,7 +144,21 @@ func (l *lexer) text() string {
 
 // emit creates a Token from the current input and calls the yield callback.
 func (l *lexer) emit(typ TokenType) {
-	if l.done || !l.yield(Token{Typ: typ, Pos: l.start, Val: l.text()}) {
+	l.emitFlags(typ, 0)
+}
+
+// emitSeparator emits a separator Token, flagging whether whitespace follows it.
+func (l *lexer) emitSeparator(typ TokenType) {
+	var flags TokenFlags
+	if isSpace(l.peek()) {
+		flags |= TokenFlagSpaceAfter
+	}
+	l.emitFlags(typ, flags)
+}
+
+// emitFlags creates a Token with the given flags and calls the yield callback.
+func (l *lexer) emitFlags(typ TokenType, flags TokenFlags) {
+	if l.done || !l.yield(Token{Typ: typ, Pos: l.start, EndPos: l.pos, Val: l.text(), Flags: flags}) {
 		l.done = true
 	}
 
@@ -79,15 +171,48 @@ func (l *lexer) ignore() {
 	l.start = l.pos
 }
 
-// errorf emits an error Token and stops lexing.
+// errorf emits an error Token at the start of the current Token and stops lexing.
 func (l *lexer) errorf(format string, args ...any) stateFn {
+	return l.errorAtf(l.start, format, args...)
+}
+
+// errorAtf emits an error Token at the given Position and stops lexing.
+func (l *lexer) errorAtf(pos Position, format string, args ...any) stateFn {
 	msg := fmt.Sprintf(format, args...)
-	l.yield(Token{Typ: TokenError, Pos: l.start, Val: msg})
+	l.yield(Token{Typ: TokenError, Pos: pos, EndPos: pos, Val: msg})
 	l.done = true
 	return nil
 }
 
+// acceptSeparator consumes the longest separator at the current Position.
+func (l *lexer) acceptSeparator() (TokenType, bool) {
+	rest := l.input[l.pos.Offset:]
+
+	typ, text := TokenError, ""
+	for _, t := range []TokenType{TokenFieldSeparator, TokenListSeparator, TokenPairSeparator} {
+		if sep := l.options.separator(t); len(sep) > len(text) && strings.HasPrefix(rest, sep) {
+			typ, text = t, sep
+		}
+	}
+	if text == "" {
+		return TokenError, false
+	}
+
+	for range utf8.RuneCountInString(text) {
+		l.next()
+	}
+	return typ, true
+}
+
 func lexTop(l *lexer) stateFn {
+	// Configured separators replace the single character cases below.
+	if l.customSeparators {
+		if typ, ok := l.acceptSeparator(); ok {
+			l.emitSeparator(typ)
+			return lexTop
+		}
+	}
+
 	switch ch := l.peek(); {
 	case ch == eof:
 		l.ignore()
@@ -97,6 +222,10 @@ func lexTop(l *lexer) stateFn {
 		l.next()
 		l.ignore()
 		return lexTop
+	case ch == badRune:
+		return l.errorAtf(l.pos, "invalid UTF-8 encoding")
+	case ch == '#':
+		return lexComment
 
 	case ch == '^' || ch == '!':
 		l.next()
@@ -104,19 +233,30 @@ func lexTop(l *lexer) stateFn {
 		return lexTop
 	case ch == '=':
 		l.next()
-		l.emit(TokenAssign)
+		l.emitSeparator(TokenAssign)
+		if l.options.GreedyValues {
+			return lexGreedyValue
+		}
+		return lexTop
+	case ch == ',' && !l.customSeparators:
+		l.next()
+		l.emitSeparator(TokenFieldSeparator)
 		return lexTop
-	case ch == ',':
+	case ch == ';' && !l.customSeparators:
 		l.next()
-		l.emit(TokenFieldSeparator)
+		l.emitSeparator(TokenListSeparator)
 		return lexTop
-	case ch == ';':
+	case ch == ':' && !l.customSeparators:
 		l.next()
-		l.emit(TokenListSeparator)
+		l.emitSeparator(TokenPairSeparator)
 		return lexTop
-	case ch == ':':
+	case ch == '(':
 		l.next()
-		l.emit(TokenPairSeparator)
+		l.emit(TokenGroupStart)
+		return lexTop
+	case ch == ')':
+		l.next()
+		l.emit(TokenGroupEnd)
 		return lexTop
 
 	case isStringStart(ch):
@@ -126,19 +266,95 @@ func lexTop(l *lexer) stateFn {
 	case isLetter(ch):
 		l.next()
 		return lexIdentifierOrKeywordContinue
+	case ch != 0 && ch == l.options.ReferencePrefix:
+		return lexReference
 
 	default:
 		return l.errorf("unexpected character: %#U", ch)
 	}
 }
 
+// lexGreedyValue scans the rest of a field as a single identifier unless it
+// is a single value on its own.
+func lexGreedyValue(l *lexer) stateFn {
+	for isSpace(l.peek()) {
+		l.next()
+	}
+	l.ignore()
+
+	rest := l.input[l.pos.Offset:]
+	if rest == "" || isStringStart(l.peek()) {
+		return lexTop
+	}
+
+	separator := l.options.separator(TokenFieldSeparator)
+	end := 0
+	for end < len(rest) && rest[end] != '#' && !strings.HasPrefix(rest[end:], separator) {
+		if rest[end] == '\\' && end+1 < len(rest) {
+			end++
+		}
+		_, w := utf8.DecodeRuneInString(rest[end:])
+		end += w
+	}
+	value := strings.TrimRightFunc(rest[:end], isSpace)
+
+	if value == "" || !utf8.ValidString(value) || l.options.isSingleValue(value) {
+		return lexTop
+	}
+
+	for range utf8.RuneCountInString(value) {
+		l.next()
+	}
+	l.emit(TokenIdentifier)
+	return lexTop
+}
+
+// isSingleValue reports whether s lexes as exactly one value token without
+// greedy values. Number limits are left to the regular number states.
+func (opt LexOptions) isSingleValue(s string) bool {
+	opt.GreedyValues, opt.MaxNumberLen = false, 0
+
+	n := 0
+	for tok := range Lex(s, opt) {
+		switch {
+		case n == 0:
+			switch tok.Typ {
+			case TokenIdentifier, TokenNumber, TokenString, TokenTrue, TokenFalse, TokenNil, TokenReference:
+			default:
+				return false
+			}
+		case n > 1 || tok.Typ != TokenEOF:
+			return false
+		}
+		n++
+	}
+	return n == 2
+}
+
+// lexComment skips a comment up to the end of the line.
+func lexComment(l *lexer) stateFn {
+	for ch := l.peek(); ch != '\n' && ch != eof; ch = l.peek() {
+		if ch == badRune {
+			return l.errorAtf(l.pos, "invalid UTF-8 encoding")
+		}
+		l.next()
+	}
+	l.ignore()
+	return lexTop
+}
+
 func lexIdentifierOrKeywordContinue(l *lexer) stateFn {
 	if isIdentifierContinue(l.peek()) {
 		l.next()
 		return lexIdentifierOrKeywordContinue
 	}
 
-	switch text := l.text(); text {
+	text := l.text()
+	if l.options.CaseInsensitiveKeywords {
+		text = strings.ToLower(text)
+	}
+
+	switch text {
 	case "true":
 		l.emit(TokenTrue)
 		return lexTop
@@ -154,6 +370,21 @@ func lexIdentifierOrKeywordContinue(l *lexer) stateFn {
 	}
 }
 
+// lexReference scans a reference prefix followed by the referenced name.
+func lexReference(l *lexer) stateFn {
+	l.next() // consume the prefix
+
+	if !isLetter(l.peek()) {
+		return l.errorAtf(l.pos, "expected name after reference prefix")
+	}
+	for isIdentifierContinue(l.peek()) {
+		l.next()
+	}
+
+	l.emit(TokenReference)
+	return lexTop
+}
+
 func lexString(l *lexer) stateFn {
 	// Get the opening quote
 	quote := l.next()
@@ -170,6 +401,8 @@ func lexStringContent(l *lexer, quote rune, fn stateFn) stateFn {
 	switch ch := l.next(); {
 	case ch == eof:
 		return l.errorf("unterminated string")
+	case ch == badRune:
+		return l.errorAtf(l.prev, "invalid UTF-8 encoding")
 	case ch == quote:
 		l.emit(TokenString)
 		return lexTop
@@ -181,8 +414,13 @@ func lexStringContent(l *lexer, quote rune, fn stateFn) stateFn {
 }
 
 func lexStringEscape(l *lexer, fn stateFn) stateFn {
-	if l.next() == eof {
-		return l.errorf("unterminated escape sequence")
+	backslash := l.prev // Position of the consumed backslash.
+
+	switch l.next() {
+	case eof:
+		return l.errorAtf(backslash, "unterminated escape sequence")
+	case badRune:
+		return l.errorAtf(l.prev, "invalid UTF-8 encoding")
 	}
 	return fn
 }
@@ -191,6 +429,16 @@ func lexNumber(l *lexer) stateFn {
 	// Handle optional sign.
 	if isNumericSign(l.peek()) {
 		l.next()
+
+		// A number may have at most one sign.
+		if isNumericSign(l.peek()) {
+			return l.errorf("invalid sign sequence")
+		}
+
+		// A sign must be followed by a number.
+		if ch := l.peek(); !isDigit(ch) && ch != '.' {
+			return l.errorAtf(l.pos, "expected digit after sign")
+		}
 	}
 
 	// Check if it's a special number (hex, octal, binary)
@@ -211,18 +459,38 @@ func lexNumber(l *lexer) stateFn {
 		case ch == '.':
 			return lexDecimalFraction
 		}
-		l.emit(TokenNumber) // Just "0"
-		return lexTop
+		return lexNumberEnd(l) // Just "0"
 	}
 
 	return lexDecimalDigits
 }
 
+// numberTooLong reports whether the number scanned so far is longer than the
+// configured maximum. The digit loops check it as they go, so that a long
+// number is rejected without reading it in full.
+func (l *lexer) numberTooLong() bool {
+	n := l.options.MaxNumberLen
+	return n > 0 && l.pos.Offset-l.start.Offset > n
+}
+
+// lexNumberEnd emits the scanned number, reporting an error if it is longer
+// than the configured maximum.
+func lexNumberEnd(l *lexer) stateFn {
+	if l.numberTooLong() {
+		return l.errorf("number literal too long")
+	}
+	l.emit(TokenNumber)
+	return lexTop
+}
+
 func lexDecimalDigits(l *lexer) stateFn {
 	ch := l.peek()
-	if isDigit(ch) || ch == '_' {
+	for isDigit(ch) || ch == '_' {
 		l.next()
-		return lexDecimalDigits
+		if l.numberTooLong() {
+			return l.errorf("number literal too long")
+		}
+		ch = l.peek()
 	}
 	if ch == '.' {
 		return lexDecimalFraction
@@ -230,8 +498,7 @@ func lexDecimalDigits(l *lexer) stateFn {
 	if ch == 'e' || ch == 'E' {
 		return lexExponent
 	}
-	l.emit(TokenNumber)
-	return lexTop
+	return lexNumberEnd(l)
 }
 
 func lexDecimalFraction(l *lexer) stateFn {
@@ -241,15 +508,17 @@ func lexDecimalFraction(l *lexer) stateFn {
 
 func lexDecimalFractionDigits(l *lexer) stateFn {
 	ch := l.peek()
-	if isDigit(ch) || ch == '_' {
+	for isDigit(ch) || ch == '_' {
 		l.next()
-		return lexDecimalFractionDigits
+		if l.numberTooLong() {
+			return l.errorf("number literal too long")
+		}
+		ch = l.peek()
 	}
 	if ch == 'e' || ch == 'E' {
 		return lexExponent
 	}
-	l.emit(TokenNumber)
-	return lexTop
+	return lexNumberEnd(l)
 }
 
 func lexExponent(l *lexer) stateFn {
@@ -262,7 +531,7 @@ func lexExponent(l *lexer) stateFn {
 
 	// Must have at least one digit
 	if !isDigit(l.peek()) {
-		return l.errorf("expected digit after exponent")
+		return l.errorAtf(l.pos, "expected digit after exponent")
 	}
 
 	return lexExponentDigits
@@ -270,27 +539,32 @@ func lexExponent(l *lexer) stateFn {
 
 func lexExponentDigits(l *lexer) stateFn {
 	ch := l.peek()
-	if isDigit(ch) || ch == '_' {
+	for isDigit(ch) || ch == '_' {
 		l.next()
-		return lexExponentDigits
+		if l.numberTooLong() {
+			return l.errorf("number literal too long")
+		}
+		ch = l.peek()
 	}
-	l.emit(TokenNumber)
-	return lexTop
+	return lexNumberEnd(l)
 }
 
 func lexHexDigits(l *lexer) stateFn {
 	// Must have at least one hex digit
 	if !isHexDigit(l.peek()) {
-		return l.errorf("expected hex digit")
+		return l.errorAtf(l.pos, "expected hex digit")
 	}
 	return lexHexDigitsContinue
 }
 
 func lexHexDigitsContinue(l *lexer) stateFn {
 	ch := l.peek()
-	if isHexDigit(ch) || ch == '_' {
+	for isHexDigit(ch) || ch == '_' {
 		l.next()
-		return lexHexDigitsContinue
+		if l.numberTooLong() {
+			return l.errorf("number literal too long")
+		}
+		ch = l.peek()
 	}
 	if ch == '.' {
 		return lexHexFraction
@@ -298,8 +572,7 @@ func lexHexDigitsContinue(l *lexer) stateFn {
 	if ch == 'p' || ch == 'P' {
 		return lexHexExponent
 	}
-	l.emit(TokenNumber)
-	return lexTop
+	return lexNumberEnd(l)
 }
 
 func lexHexFraction(l *lexer) stateFn {
@@ -309,15 +582,17 @@ func lexHexFraction(l *lexer) stateFn {
 
 func lexHexFractionDigits(l *lexer) stateFn {
 	ch := l.peek()
-	if isHexDigit(ch) || ch == '_' {
+	for isHexDigit(ch) || ch == '_' {
 		l.next()
-		return lexHexFractionDigits
+		if l.numberTooLong() {
+			return l.errorf("number literal too long")
+		}
+		ch = l.peek()
 	}
 	if ch == 'p' || ch == 'P' {
 		return lexHexExponent
 	}
-	l.emit(TokenNumber)
-	return lexTop
+	return lexNumberEnd(l)
 }
 
 func lexHexExponent(l *lexer) stateFn {
@@ -330,7 +605,7 @@ func lexHexExponent(l *lexer) stateFn {
 
 	// Must have at least one digit
 	if !isDigit(l.peek()) {
-		return l.errorf("expected digit after hex exponent")
+		return l.errorAtf(l.pos, "expected digit after hex exponent")
 	}
 
 	return lexHexExponentDigits
@@ -338,48 +613,54 @@ func lexHexExponent(l *lexer) stateFn {
 
 func lexHexExponentDigits(l *lexer) stateFn {
 	ch := l.peek()
-	if isDigit(ch) || ch == '_' {
+	for isDigit(ch) || ch == '_' {
 		l.next()
-		return lexHexExponentDigits
+		if l.numberTooLong() {
+			return l.errorf("number literal too long")
+		}
+		ch = l.peek()
 	}
-	l.emit(TokenNumber)
-	return lexTop
+	return lexNumberEnd(l)
 }
 
 func lexOctalDigits(l *lexer) stateFn {
 	// Must have at least one octal digit
 	if !isOctalDigit(l.peek()) {
-		return l.errorf("expected octal digit")
+		return l.errorAtf(l.pos, "expected octal digit")
 	}
 	return lexOctalDigitsContinue
 }
 
 func lexOctalDigitsContinue(l *lexer) stateFn {
 	ch := l.peek()
-	if isOctalDigit(ch) || ch == '_' {
+	for isOctalDigit(ch) || ch == '_' {
 		l.next()
-		return lexOctalDigitsContinue
+		if l.numberTooLong() {
+			return l.errorf("number literal too long")
+		}
+		ch = l.peek()
 	}
-	l.emit(TokenNumber)
-	return lexTop
+	return lexNumberEnd(l)
 }
 
 func lexBinaryDigits(l *lexer) stateFn {
 	// Must have at least one binary digit
 	if !isBinaryDigit(l.peek()) {
-		return l.errorf("expected binary digit")
+		return l.errorAtf(l.pos, "expected binary digit")
 	}
 	return lexBinaryDigitsContinue
 }
 
 func lexBinaryDigitsContinue(l *lexer) stateFn {
 	ch := l.peek()
-	if isBinaryDigit(ch) || ch == '_' {
+	for isBinaryDigit(ch) || ch == '_' {
 		l.next()
-		return lexBinaryDigitsContinue
+		if l.numberTooLong() {
+			return l.errorf("number literal too long")
+		}
+		ch = l.peek()
 	}
-	l.emit(TokenNumber)
-	return lexTop
+	return lexNumberEnd(l)
 }
 
 func runPattern(l *lexer) {
@@ -391,11 +672,19 @@ func runPattern(l *lexer) {
 }
 
 // Lex returns a lazy iterator lexer for the input yielding tokens.
-func Lex(input string) iter.Seq[Token] {
+func Lex(input string, opts ...LexOptions) iter.Seq[Token] {
+	opt := LexDefaults()
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
 	return func(yield func(Token) bool) {
 		l := &lexer{
-			input: input,
-			yield: yield,
+			input:   input,
+			options: opt,
+			yield:   yield,
+
+			customSeparators: opt.hasCustomSeparators(),
 
 			// Initialize positions: starting at offset 0, line 1, column 1.
 			start: Position{Offset: 0, Column: 1},
@@ -3,7 +3,10 @@ package kaval
 import (
 	"reflect"
 	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestLex(t *testing.T) {
@@ -186,6 +189,26 @@ func TestLex(t *testing.T) {
 			{Typ: TokenNumber, Pos: Position{Offset: 10, Column: 11}, Val: "0b1101"},
 			{Typ: TokenEOF, Pos: Position{Offset: 16, Column: 17}, Val: ""},
 		}},
+		{"signed fraction", "a=-.5", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenNumber, Pos: Position{Offset: 2, Column: 3}, Val: "-.5"},
+			{Typ: TokenEOF, Pos: Position{Offset: 5, Column: 6}, Val: ""},
+		}},
+		{"signed hex, octal and binary numbers", "h=-0xFF,b=+0b101,o=-0o17", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "h"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenNumber, Pos: Position{Offset: 2, Column: 3}, Val: "-0xFF"},
+			{Typ: TokenFieldSeparator, Pos: Position{Offset: 7, Column: 8}, Val: ","},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 8, Column: 9}, Val: "b"},
+			{Typ: TokenAssign, Pos: Position{Offset: 9, Column: 10}, Val: "="},
+			{Typ: TokenNumber, Pos: Position{Offset: 10, Column: 11}, Val: "+0b101"},
+			{Typ: TokenFieldSeparator, Pos: Position{Offset: 16, Column: 17}, Val: ","},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 17, Column: 18}, Val: "o"},
+			{Typ: TokenAssign, Pos: Position{Offset: 18, Column: 19}, Val: "="},
+			{Typ: TokenNumber, Pos: Position{Offset: 19, Column: 20}, Val: "-0o17"},
+			{Typ: TokenEOF, Pos: Position{Offset: 24, Column: 25}, Val: ""},
+		}},
 		{"string literals", `s1="hello",s2='world'`, []Token{
 			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "s1"},
 			{Typ: TokenAssign, Pos: Position{Offset: 2, Column: 3}, Val: "="},
@@ -202,16 +225,71 @@ func TestLex(t *testing.T) {
 			{Typ: TokenString, Pos: Position{Offset: 2, Column: 3}, Val: `"a\nb\tc\"d"`},
 			{Typ: TokenEOF, Pos: Position{Offset: 14, Column: 15}, Val: ""},
 		}},
+		// Positions have no line, columns keep counting across newlines.
+		{"string with embedded newline", "s=\"a\nb\",t=1", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "s"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenString, Pos: Position{Offset: 2, Column: 3}, Val: "\"a\nb\""},
+			{Typ: TokenFieldSeparator, Pos: Position{Offset: 7, Column: 8}, Val: ","},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 8, Column: 9}, Val: "t"},
+			{Typ: TokenAssign, Pos: Position{Offset: 9, Column: 10}, Val: "="},
+			{Typ: TokenNumber, Pos: Position{Offset: 10, Column: 11}, Val: "1"},
+			{Typ: TokenEOF, Pos: Position{Offset: 11, Column: 12}, Val: ""},
+		}},
 		{"whitespace handling", "  a  =  123  ,  b  =  true  ", []Token{
 			{Typ: TokenIdentifier, Pos: Position{Offset: 2, Column: 3}, Val: "a"},
-			{Typ: TokenAssign, Pos: Position{Offset: 5, Column: 6}, Val: "="},
+			{Typ: TokenAssign, Pos: Position{Offset: 5, Column: 6}, Val: "=", Flags: TokenFlagSpaceAfter},
 			{Typ: TokenNumber, Pos: Position{Offset: 8, Column: 9}, Val: "123"},
-			{Typ: TokenFieldSeparator, Pos: Position{Offset: 13, Column: 14}, Val: ","},
+			{Typ: TokenFieldSeparator, Pos: Position{Offset: 13, Column: 14}, Val: ",", Flags: TokenFlagSpaceAfter},
 			{Typ: TokenIdentifier, Pos: Position{Offset: 16, Column: 17}, Val: "b"},
-			{Typ: TokenAssign, Pos: Position{Offset: 19, Column: 20}, Val: "="},
+			{Typ: TokenAssign, Pos: Position{Offset: 19, Column: 20}, Val: "=", Flags: TokenFlagSpaceAfter},
 			{Typ: TokenTrue, Pos: Position{Offset: 22, Column: 23}, Val: "true"},
 			{Typ: TokenEOF, Pos: Position{Offset: 28, Column: 29}, Val: ""},
 		}},
+		{"space after separators", "a=1, b=x; y", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenNumber, Pos: Position{Offset: 2, Column: 3}, Val: "1"},
+			{Typ: TokenFieldSeparator, Pos: Position{Offset: 3, Column: 4}, Val: ",", Flags: TokenFlagSpaceAfter},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 5, Column: 6}, Val: "b"},
+			{Typ: TokenAssign, Pos: Position{Offset: 6, Column: 7}, Val: "="},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 7, Column: 8}, Val: "x"},
+			{Typ: TokenListSeparator, Pos: Position{Offset: 8, Column: 9}, Val: ";", Flags: TokenFlagSpaceAfter},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 10, Column: 11}, Val: "y"},
+			{Typ: TokenEOF, Pos: Position{Offset: 11, Column: 12}, Val: ""},
+		}},
+		{"no space after separators", "a=1,b=2", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenNumber, Pos: Position{Offset: 2, Column: 3}, Val: "1"},
+			{Typ: TokenFieldSeparator, Pos: Position{Offset: 3, Column: 4}, Val: ","},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 4, Column: 5}, Val: "b"},
+			{Typ: TokenAssign, Pos: Position{Offset: 5, Column: 6}, Val: "="},
+			{Typ: TokenNumber, Pos: Position{Offset: 6, Column: 7}, Val: "2"},
+			{Typ: TokenEOF, Pos: Position{Offset: 7, Column: 8}, Val: ""},
+		}},
+		{"trailing comment", "a=1 # inline note", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenNumber, Pos: Position{Offset: 2, Column: 3}, Val: "1"},
+			{Typ: TokenEOF, Pos: Position{Offset: 17, Column: 18}, Val: ""},
+		}},
+		{"comment lines", "# leading\na=1,# between\nb=x#trailing", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 10, Column: 11}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 11, Column: 12}, Val: "="},
+			{Typ: TokenNumber, Pos: Position{Offset: 12, Column: 13}, Val: "1"},
+			{Typ: TokenFieldSeparator, Pos: Position{Offset: 13, Column: 14}, Val: ","},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 24, Column: 25}, Val: "b"},
+			{Typ: TokenAssign, Pos: Position{Offset: 25, Column: 26}, Val: "="},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 26, Column: 27}, Val: "x"},
+			{Typ: TokenEOF, Pos: Position{Offset: 36, Column: 37}, Val: ""},
+		}},
+		{"hash inside string", `a="# not a comment"`, []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenString, Pos: Position{Offset: 2, Column: 3}, Val: `"# not a comment"`},
+			{Typ: TokenEOF, Pos: Position{Offset: 19, Column: 20}, Val: ""},
+		}},
 
 		// Error cases.
 		{"error: unexpected character", "a=@", []Token{
@@ -224,40 +302,123 @@ func TestLex(t *testing.T) {
 			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
 			{Typ: TokenError, Pos: Position{Offset: 2, Column: 3}, Val: "unterminated string"},
 		}},
+		{"error: unterminated escape sequence at backslash", `s="ab\`, []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "s"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenError, Pos: Position{Offset: 5, Column: 6}, Val: "unterminated escape sequence"},
+		}},
 		{"error: unterminated escape sequence", `s="hello\`, []Token{
 			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "s"},
 			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
-			{Typ: TokenError, Pos: Position{Offset: 2, Column: 3}, Val: "unterminated escape sequence"},
+			{Typ: TokenError, Pos: Position{Offset: 8, Column: 9}, Val: "unterminated escape sequence"},
+		}},
+		{"error: lone sign", "a=-", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenError, Pos: Position{Offset: 3, Column: 4}, Val: "expected digit after sign"},
+		}},
+		{"error: lone sign before field separator", "a=-,b=1", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenError, Pos: Position{Offset: 3, Column: 4}, Val: "expected digit after sign"},
+		}},
+		{"error: sign before identifier", "a=+x", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenError, Pos: Position{Offset: 3, Column: 4}, Val: "expected digit after sign"},
+		}},
+		{"error: double minus sign", "--5", []Token{
+			{Typ: TokenError, Pos: Position{Offset: 0, Column: 1}, Val: "invalid sign sequence"},
+		}},
+		{"error: plus minus sign", "a=+-5", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenError, Pos: Position{Offset: 2, Column: 3}, Val: "invalid sign sequence"},
+		}},
+		{"error: minus plus sign in list", "a=1;-+5", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenNumber, Pos: Position{Offset: 2, Column: 3}, Val: "1"},
+			{Typ: TokenListSeparator, Pos: Position{Offset: 3, Column: 4}, Val: ";"},
+			{Typ: TokenError, Pos: Position{Offset: 4, Column: 5}, Val: "invalid sign sequence"},
+		}},
+		{"error: invalid UTF-8", "\xff\xfe", []Token{
+			{Typ: TokenError, Pos: Position{Offset: 0, Column: 1}, Val: "invalid UTF-8 encoding"},
+		}},
+		{"error: invalid UTF-8 after identifier", "ab\xff=1", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "ab"},
+			{Typ: TokenError, Pos: Position{Offset: 2, Column: 3}, Val: "invalid UTF-8 encoding"},
+		}},
+		{"error: invalid UTF-8 in string", "s=\"é\xffb\"", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "s"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenError, Pos: Position{Offset: 5, Column: 5}, Val: "invalid UTF-8 encoding"},
+		}},
+		{"error: invalid UTF-8 in escape", "s='\\\xff'", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "s"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenError, Pos: Position{Offset: 4, Column: 5}, Val: "invalid UTF-8 encoding"},
+		}},
+		{"error: invalid UTF-8 in comment", "a=1 # \xfe", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenNumber, Pos: Position{Offset: 2, Column: 3}, Val: "1"},
+			{Typ: TokenError, Pos: Position{Offset: 6, Column: 7}, Val: "invalid UTF-8 encoding"},
+		}},
+		{"replacement character is valid", "s=\"\uFFFD\"", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "s"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenString, Pos: Position{Offset: 2, Column: 3}, Val: "\"\uFFFD\""},
+			{Typ: TokenEOF, Pos: Position{Offset: 7, Column: 6}, Val: ""},
+		}},
+		{"groups", "m=k:(a;b)", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "m"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 2, Column: 3}, Val: "k"},
+			{Typ: TokenPairSeparator, Pos: Position{Offset: 3, Column: 4}, Val: ":"},
+			{Typ: TokenGroupStart, Pos: Position{Offset: 4, Column: 5}, Val: "("},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 5, Column: 6}, Val: "a"},
+			{Typ: TokenListSeparator, Pos: Position{Offset: 6, Column: 7}, Val: ";"},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 7, Column: 8}, Val: "b"},
+			{Typ: TokenGroupEnd, Pos: Position{Offset: 8, Column: 9}, Val: ")"},
+			{Typ: TokenEOF, Pos: Position{Offset: 9, Column: 10}, Val: ""},
 		}},
 		{"error: exponent missing digits", "e=1e", []Token{
 			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "e"},
 			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
-			{Typ: TokenError, Pos: Position{Offset: 2, Column: 3}, Val: "expected digit after exponent"},
+			{Typ: TokenError, Pos: Position{Offset: 4, Column: 5}, Val: "expected digit after exponent"},
 		}},
 		{"error: invalid hex number", "h=0xGHI", []Token{
 			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "h"},
 			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
-			{Typ: TokenError, Pos: Position{Offset: 2, Column: 3}, Val: "expected hex digit"},
+			{Typ: TokenError, Pos: Position{Offset: 4, Column: 5}, Val: "expected hex digit"},
 		}},
 		{"error: invalid hex exponent", "h=0x1p+G", []Token{
 			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "h"},
 			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
-			{Typ: TokenError, Pos: Position{Offset: 2, Column: 3}, Val: "expected digit after hex exponent"},
+			{Typ: TokenError, Pos: Position{Offset: 7, Column: 8}, Val: "expected digit after hex exponent"},
 		}},
 		{"error: invalid octal number", "o=0o8", []Token{
 			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "o"},
 			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
-			{Typ: TokenError, Pos: Position{Offset: 2, Column: 3}, Val: "expected octal digit"},
+			{Typ: TokenError, Pos: Position{Offset: 4, Column: 5}, Val: "expected octal digit"},
 		}},
 		{"error: invalid binary number", "b=0b2", []Token{
 			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "b"},
 			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
-			{Typ: TokenError, Pos: Position{Offset: 2, Column: 3}, Val: "expected binary digit"},
+			{Typ: TokenError, Pos: Position{Offset: 4, Column: 5}, Val: "expected binary digit"},
+		}},
+		{"error: invalid hex digit after prefix in list", "h=1;0xG", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "h"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenNumber, Pos: Position{Offset: 2, Column: 3}, Val: "1"},
+			{Typ: TokenListSeparator, Pos: Position{Offset: 3, Column: 4}, Val: ";"},
+			{Typ: TokenError, Pos: Position{Offset: 6, Column: 7}, Val: "expected hex digit"},
 		}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := slices.Collect(Lex(tt.input))
+			got := withoutEndPos(t, slices.Collect(Lex(tt.input)))
 
 			if !reflect.DeepEqual(got, tt.expected) {
 				t.Errorf("Lex(%q) =\n  got:  %v\n  want: %v", tt.input, got, tt.expected)
@@ -265,3 +426,201 @@ func TestLex(t *testing.T) {
 		})
 	}
 }
+
+// withoutEndPos checks that every token ends right after its text, or at
+// its start for errors, and clears the end positions so that expected
+// tokens only list their start.
+func withoutEndPos(t *testing.T, tokens []Token) []Token {
+	t.Helper()
+
+	for i, tok := range tokens {
+		want := tok.Pos.Add(len(tok.Val), utf8.RuneCountInString(tok.Val))
+		if tok.Typ == TokenError {
+			want = tok.Pos
+		}
+		if tok.EndPos != want {
+			t.Errorf("%v ends at %v, want %v", tok, tok.EndPos, want)
+		}
+		tokens[i].EndPos = Position{}
+	}
+	return tokens
+}
+
+func TestLex_EndPos(t *testing.T) {
+	input := `a = "é" ,b`
+	expected := []Token{
+		{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, EndPos: Position{Offset: 1, Column: 2}, Val: "a"},
+		{Typ: TokenAssign, Pos: Position{Offset: 2, Column: 3}, EndPos: Position{Offset: 3, Column: 4}, Val: "=", Flags: TokenFlagSpaceAfter},
+		{Typ: TokenString, Pos: Position{Offset: 4, Column: 5}, EndPos: Position{Offset: 8, Column: 8}, Val: `"é"`},
+		{Typ: TokenFieldSeparator, Pos: Position{Offset: 9, Column: 9}, EndPos: Position{Offset: 10, Column: 10}, Val: ","},
+		{Typ: TokenIdentifier, Pos: Position{Offset: 10, Column: 10}, EndPos: Position{Offset: 11, Column: 11}, Val: "b"},
+		{Typ: TokenEOF, Pos: Position{Offset: 11, Column: 11}, EndPos: Position{Offset: 11, Column: 11}, Val: ""},
+	}
+
+	got := slices.Collect(Lex(input))
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Lex(%q) =\n  got:  %v\n  want: %v", input, got, expected)
+	}
+
+	// Lexing resumes from the end of a token.
+	if rest := input[got[2].EndPos.Offset:]; rest != " ,b" {
+		t.Errorf("input after string = %q, want %q", rest, " ,b")
+	}
+}
+
+func TestLexOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  LexOptions
+		input    string
+		expected []Token
+	}{
+		{"uppercase keywords are identifiers by default", LexOptions{}, "TRUE;False;NIL", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "TRUE"},
+			{Typ: TokenListSeparator, Pos: Position{Offset: 4, Column: 5}, Val: ";"},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 5, Column: 6}, Val: "False"},
+			{Typ: TokenListSeparator, Pos: Position{Offset: 10, Column: 11}, Val: ";"},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 11, Column: 12}, Val: "NIL"},
+			{Typ: TokenEOF, Pos: Position{Offset: 14, Column: 15}, Val: ""},
+		}},
+		{"case-insensitive keywords", LexOptions{CaseInsensitiveKeywords: true}, "TRUE;False;NIL", []Token{
+			{Typ: TokenTrue, Pos: Position{Offset: 0, Column: 1}, Val: "TRUE"},
+			{Typ: TokenListSeparator, Pos: Position{Offset: 4, Column: 5}, Val: ";"},
+			{Typ: TokenFalse, Pos: Position{Offset: 5, Column: 6}, Val: "False"},
+			{Typ: TokenListSeparator, Pos: Position{Offset: 10, Column: 11}, Val: ";"},
+			{Typ: TokenNil, Pos: Position{Offset: 11, Column: 12}, Val: "NIL"},
+			{Typ: TokenEOF, Pos: Position{Offset: 14, Column: 15}, Val: ""},
+		}},
+		{"greedy values", LexOptions{GreedyValues: true}, `q=a=b, r = k:v;w ,s=a\,b`, []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "q"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 2, Column: 3}, Val: "a=b"},
+			{Typ: TokenFieldSeparator, Pos: Position{Offset: 5, Column: 6}, Val: ",", Flags: TokenFlagSpaceAfter},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 7, Column: 8}, Val: "r"},
+			{Typ: TokenAssign, Pos: Position{Offset: 9, Column: 10}, Val: "=", Flags: TokenFlagSpaceAfter},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 11, Column: 12}, Val: "k:v;w"},
+			{Typ: TokenFieldSeparator, Pos: Position{Offset: 17, Column: 18}, Val: ","},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 18, Column: 19}, Val: "s"},
+			{Typ: TokenAssign, Pos: Position{Offset: 19, Column: 20}, Val: "="},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 20, Column: 21}, Val: `a\,b`},
+			{Typ: TokenEOF, Pos: Position{Offset: 24, Column: 25}, Val: ""},
+		}},
+		{"greedy values keep single values", LexOptions{GreedyValues: true}, `a=-1,b="x=y",c=true # a=b`, []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenNumber, Pos: Position{Offset: 2, Column: 3}, Val: "-1"},
+			{Typ: TokenFieldSeparator, Pos: Position{Offset: 4, Column: 5}, Val: ","},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 5, Column: 6}, Val: "b"},
+			{Typ: TokenAssign, Pos: Position{Offset: 6, Column: 7}, Val: "="},
+			{Typ: TokenString, Pos: Position{Offset: 7, Column: 8}, Val: `"x=y"`},
+			{Typ: TokenFieldSeparator, Pos: Position{Offset: 12, Column: 13}, Val: ","},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 13, Column: 14}, Val: "c"},
+			{Typ: TokenAssign, Pos: Position{Offset: 14, Column: 15}, Val: "="},
+			{Typ: TokenTrue, Pos: Position{Offset: 15, Column: 16}, Val: "true"},
+			{Typ: TokenEOF, Pos: Position{Offset: 25, Column: 26}, Val: ""},
+		}},
+		{"number within max length", LexOptions{MaxNumberLen: 4}, "a=-1.5;0xFF", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenNumber, Pos: Position{Offset: 2, Column: 3}, Val: "-1.5"},
+			{Typ: TokenListSeparator, Pos: Position{Offset: 6, Column: 7}, Val: ";"},
+			{Typ: TokenNumber, Pos: Position{Offset: 7, Column: 8}, Val: "0xFF"},
+			{Typ: TokenEOF, Pos: Position{Offset: 11, Column: 12}, Val: ""},
+		}},
+		{"number exceeds max length", LexOptions{MaxNumberLen: 4}, "a=1;12345", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenNumber, Pos: Position{Offset: 2, Column: 3}, Val: "1"},
+			{Typ: TokenListSeparator, Pos: Position{Offset: 3, Column: 4}, Val: ";"},
+			{Typ: TokenError, Pos: Position{Offset: 4, Column: 5}, Val: "number literal too long"},
+		}},
+		{"number is rejected before it is read in full", LexOptions{MaxNumberLen: 4}, "a=123456e", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenError, Pos: Position{Offset: 2, Column: 3}, Val: "number literal too long"},
+		}},
+		{"greedy number exceeds max length", LexOptions{MaxNumberLen: 4, GreedyValues: true}, "a=0x12345", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenError, Pos: Position{Offset: 2, Column: 3}, Val: "number literal too long"},
+		}},
+		{"reference prefix is rejected by default", LexOptions{}, "home=@HOME", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "home"},
+			{Typ: TokenAssign, Pos: Position{Offset: 4, Column: 5}, Val: "="},
+			{Typ: TokenError, Pos: Position{Offset: 5, Column: 6}, Val: "unexpected character: U+0040 '@'"},
+		}},
+		{"reference", LexOptions{ReferencePrefix: '@'}, "home=@HOME;@user-dir", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "home"},
+			{Typ: TokenAssign, Pos: Position{Offset: 4, Column: 5}, Val: "="},
+			{Typ: TokenReference, Pos: Position{Offset: 5, Column: 6}, Val: "@HOME"},
+			{Typ: TokenListSeparator, Pos: Position{Offset: 10, Column: 11}, Val: ";"},
+			{Typ: TokenReference, Pos: Position{Offset: 11, Column: 12}, Val: "@user-dir"},
+			{Typ: TokenEOF, Pos: Position{Offset: 20, Column: 21}, Val: ""},
+		}},
+		{"reference without name", LexOptions{ReferencePrefix: '$'}, "a=$,b", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenError, Pos: Position{Offset: 3, Column: 4}, Val: "expected name after reference prefix"},
+		}},
+		{"multi-rune pair separator", LexOptions{PairSeparator: "::"}, "a::1", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenPairSeparator, Pos: Position{Offset: 1, Column: 2}, Val: "::"},
+			{Typ: TokenNumber, Pos: Position{Offset: 3, Column: 4}, Val: "1"},
+			{Typ: TokenEOF, Pos: Position{Offset: 4, Column: 5}, Val: ""},
+		}},
+		{"multi-rune separators with defaults", LexOptions{FieldSeparator: ",,", PairSeparator: "::"}, "m=a:: 1;b::2,, c", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "m"},
+			{Typ: TokenAssign, Pos: Position{Offset: 1, Column: 2}, Val: "="},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 2, Column: 3}, Val: "a"},
+			{Typ: TokenPairSeparator, Pos: Position{Offset: 3, Column: 4}, Val: "::", Flags: TokenFlagSpaceAfter},
+			{Typ: TokenNumber, Pos: Position{Offset: 6, Column: 7}, Val: "1"},
+			{Typ: TokenListSeparator, Pos: Position{Offset: 7, Column: 8}, Val: ";"},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 8, Column: 9}, Val: "b"},
+			{Typ: TokenPairSeparator, Pos: Position{Offset: 9, Column: 10}, Val: "::"},
+			{Typ: TokenNumber, Pos: Position{Offset: 11, Column: 12}, Val: "2"},
+			{Typ: TokenFieldSeparator, Pos: Position{Offset: 12, Column: 13}, Val: ",,", Flags: TokenFlagSpaceAfter},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 15, Column: 16}, Val: "c"},
+			{Typ: TokenEOF, Pos: Position{Offset: 16, Column: 17}, Val: ""},
+		}},
+		{"longest separator wins", LexOptions{ListSeparator: ":", PairSeparator: "::"}, "a:b::c", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenListSeparator, Pos: Position{Offset: 1, Column: 2}, Val: ":"},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 2, Column: 3}, Val: "b"},
+			{Typ: TokenPairSeparator, Pos: Position{Offset: 3, Column: 4}, Val: "::"},
+			{Typ: TokenIdentifier, Pos: Position{Offset: 5, Column: 6}, Val: "c"},
+			{Typ: TokenEOF, Pos: Position{Offset: 6, Column: 7}, Val: ""},
+		}},
+		{"partial multi-rune separator", LexOptions{PairSeparator: "::"}, "a:1", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "a"},
+			{Typ: TokenError, Pos: Position{Offset: 1, Column: 2}, Val: "unexpected character: U+003A ':'"},
+		}},
+		{"case-insensitive keywords keep identifiers", LexOptions{CaseInsensitiveKeywords: true}, "Trueish", []Token{
+			{Typ: TokenIdentifier, Pos: Position{Offset: 0, Column: 1}, Val: "Trueish"},
+			{Typ: TokenEOF, Pos: Position{Offset: 7, Column: 8}, Val: ""},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := withoutEndPos(t, slices.Collect(Lex(tt.input, tt.options)))
+
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Lex(%q) =\n  got:  %v\n  want: %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func BenchmarkLex_LongNumber(b *testing.B) {
+	for _, size := range []int{1 << 10, 1 << 20} {
+		input := strings.Repeat("1", size)
+
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			for b.Loop() {
+				for range Lex(input) {
+				}
+			}
+		})
+	}
+}
@@ -10,7 +10,106 @@ import (
 // ParseOptions holds options for parsing.
 type ParseOptions struct {
 	// AllowOrdered allows ordered values without a key.
+	//
+	// Deprecated: Ordered values are allowed unless RejectOrdered is set, as
+	// Parse and ParseTokens fill in AllowOrdered with WithDefaults.
 	AllowOrdered bool
+
+	// RejectOrdered reports an error for ordered values without a key.
+	RejectOrdered bool
+
+	// TreatTopLevelListSepAsField reads a list separator after an ordered
+	// value as a field separator, e.g. a;b;c as three ordered values instead
+	// of a single list. Lists assigned to labeled fields are not affected.
+	TreatTopLevelListSepAsField bool
+
+	// ForceRoot forces the top level of the input to be a list or a map
+	// regardless of its content. Fields of the other kind are reported as
+	// errors, and an empty input yields an empty list or map.
+	ForceRoot Root
+
+	// MaxDepth limits the nesting of lists and maps, counting the top-level
+	// field list as the first level, e.g. 1 only allows single values in
+	// fields. Zero means no limit.
+	MaxDepth int
+
+	// TypeAnnotations enables type hints in front of single values, e.g.
+	// port=int:8080. The value must match the annotated type, otherwise an
+	// error is reported. The reserved type names are int, uint, float, bool
+	// and string, so maps whose first key is one of them cannot be written
+	// with this option enabled.
+	TypeAnnotations bool
+
+	// RequireFullConsume reports an error for any content following a field
+	// other than a field separator or the end of the input, instead of
+	// leaving it unparsed.
+	RequireFullConsume bool
+
+	// Lex holds the options for lexing the input of Parse.
+	Lex LexOptions
+
+	// BareKeyAsTrue treats a bare identifier where an ordered value is not
+	// allowed, e.g. after a labeled field, as a boolean field set to true.
+	BareKeyAsTrue bool
+
+	// Intern reuses the Value of identical identifier and string tokens
+	// within an input, e.g. keys repeated in every record of a log, instead
+	// of allocating a new Value for each of them.
+	Intern bool
+
+	// RejectNonStringKeys reports an error for map keys other than
+	// identifiers, strings and numbers, e.g. true:1 or nil:x.
+	RejectNonStringKeys bool
+
+	// recoverErrors continues parsing at the next field separator after an
+	// error instead of stopping, see Validate. The events of the malformed
+	// field are not balanced by their end events. Lexer errors always stop
+	// parsing.
+	recoverErrors bool
+}
+
+// typeAnnotations maps the reserved type names to a check whether a value
+// token is of that type.
+var typeAnnotations = map[string]func(Token) bool{
+	"int": func(t Token) bool {
+		return t.Typ == TokenNumber && CanInt(NumberValue{t.Val})
+	},
+	"uint": func(t Token) bool {
+		return t.Typ == TokenNumber && CanUint(NumberValue{t.Val})
+	},
+	"float": func(t Token) bool {
+		return t.Typ == TokenNumber && CanFloat(NumberValue{t.Val})
+	},
+	"bool": func(t Token) bool {
+		return t.Typ == TokenTrue || t.Typ == TokenFalse
+	},
+	"string": func(t Token) bool {
+		return t.Typ == TokenString || t.Typ == TokenIdentifier
+	},
+}
+
+// Root selects the kind of the top level of a parsed input.
+type Root int
+
+const (
+	// RootAuto makes the top level a list for ordered values and a map for
+	// labeled fields, or both in this order.
+	RootAuto Root = iota
+
+	// RootList makes the top level a list of ordered values.
+	RootList
+
+	// RootMap makes the top level a map of labeled fields.
+	RootMap
+)
+
+// WithDefaults returns the options with the fields whose zero value does not
+// mean the default filled in from ParseDefaults, so that options such as
+// ParseOptions{MaxDepth: 5} do not disable ordered values by accident.
+// Parse and ParseTokens apply it to the given options.
+func (o ParseOptions) WithDefaults() ParseOptions {
+	o.AllowOrdered = !o.RejectOrdered
+	return o
 }
 
 // ParseDefaults returns the default parsing options.
@@ -99,6 +198,9 @@ type Parser struct {
 	hasToken bool
 
 	state parserState
+	depth int // Number of open lists and maps below the top level.
+
+	interned map[string]Value // Values by token text, see ParseOptions.Intern.
 }
 
 // emit sends an event through yield.
@@ -135,6 +237,9 @@ func (p *Parser) isNext(typ ...TokenType) bool {
 }
 
 // advance advances to the next token.
+//
+// A lexer error token is reported with its own message and position and
+// stops the parser.
 func (p *Parser) advance() bool {
 	if p.peeked != nil {
 		// If we have a peeked token, consume it.
@@ -151,6 +256,12 @@ func (p *Parser) advance() bool {
 		p.hasToken = false
 	}
 
+	if p.hasToken && p.current.Typ == TokenError {
+		p.errorf("%s", p.current.Val)
+		p.done = true
+		return false
+	}
+
 	return p.hasToken
 }
 
@@ -164,7 +275,21 @@ func (p *Parser) isToken(typ TokenType) bool {
 
 // toValue converts the current token to a Value.
 func (p *Parser) toValue() Value {
-	return valueFromToken(p.current)
+	if !p.config.Intern || (p.current.Typ != TokenIdentifier && p.current.Typ != TokenString) {
+		return valueFromToken(p.current)
+	}
+
+	// The text of identifiers and strings cannot collide, as only strings
+	// start with a quote.
+	if v, ok := p.interned[p.current.Val]; ok {
+		return v
+	}
+	if p.interned == nil {
+		p.interned = make(map[string]Value)
+	}
+	v := valueFromToken(p.current)
+	p.interned[p.current.Val] = v
+	return v
 }
 
 // emitValueEvent emits the current token as a ValueEvent.
@@ -191,6 +316,12 @@ func (p *Parser) updateState(newState parserState) {
 			p.emit(ListEndEvent{})
 		} else if p.state == labeledState {
 			p.emit(MapEndEvent{})
+		} else if p.state == startState && p.config.ForceRoot == RootList {
+			p.emit(ListStartEvent{})
+			p.emit(ListEndEvent{})
+		} else if p.state == startState && p.config.ForceRoot == RootMap {
+			p.emit(MapStartEvent{})
+			p.emit(MapEndEvent{})
 		}
 	}
 
@@ -204,8 +335,11 @@ func (p *Parser) parseFieldList() {
 			break
 		}
 
-		if !p.parseField() {
-			return
+		if !p.parseField() || !p.isFieldEnd() {
+			if !p.config.recoverErrors || !p.skipField() {
+				return
+			}
+			continue
 		}
 
 		// If there's a field separator, consume it.
@@ -216,36 +350,103 @@ func (p *Parser) parseFieldList() {
 	p.updateState(eofState)
 }
 
+// isFieldEnd checks that a parsed field is followed by a field separator or
+// the end of the input if RequireFullConsume is set.
+func (p *Parser) isFieldEnd() bool {
+	if !p.config.RequireFullConsume || !p.hasToken {
+		return true
+	}
+
+	switch p.current.Typ {
+	case TokenFieldSeparator, TokenEOF:
+		return true
+	case TokenListSeparator:
+		if p.config.TreatTopLevelListSepAsField && p.state == orderedState {
+			return true
+		}
+		fallthrough
+	default:
+		return p.errorf("unexpected %s after field", p.current.Typ)
+	}
+}
+
+// skipField skips the remaining tokens of a malformed field. It reports
+// whether a field separator was reached and parsing can continue.
+func (p *Parser) skipField() bool {
+	for p.hasToken && !p.done {
+		switch p.current.Typ {
+		case TokenFieldSeparator:
+			return true
+		case TokenEOF:
+			return false
+		}
+		p.advance()
+	}
+	return false
+}
+
 // parseField parses a single field
 func (p *Parser) parseField() bool {
 	switch p.current.Typ {
 	case TokenBooleanPrefix:
+		if p.config.ForceRoot == RootList {
+			return p.errorf("labeled field not allowed here")
+		}
 		p.updateState(labeledState)
 		return p.parseBooleanPrefix()
 
 	case TokenIdentifier:
 		// Check if this is a labeled field assignment.
 		if p.isNext(TokenAssign) {
+			if p.config.ForceRoot == RootList {
+				return p.errorf("labeled field not allowed here")
+			}
 			p.updateState(labeledState)
 			return p.parseAssignment()
 		}
 
+		// A bare identifier may be a shorthand for a boolean field.
+		if p.config.BareKeyAsTrue && !p.orderedAllowed() && p.config.ForceRoot != RootList && p.isNext(TokenFieldSeparator, TokenEOF) {
+			p.updateState(labeledState)
+			return p.parseBareKey()
+		}
+
 		// If it's not an assignment, treat it as an ordered value.
 		fallthrough
 
-	case TokenString, TokenNumber, TokenTrue, TokenFalse, TokenNil:
-		if !p.config.AllowOrdered || p.state > orderedState {
+	case TokenString, TokenNumber, TokenTrue, TokenFalse, TokenNil, TokenReference:
+		if !p.orderedAllowed() {
 			return p.errorf("ordered value not allowed here")
 		}
 		p.updateState(orderedState)
 
+		if p.config.TreatTopLevelListSepAsField && p.isValue() && p.isNext(TokenListSeparator) {
+			// The list separator ends the field instead of starting a list.
+			return p.emitValueEvent() && p.advance()
+		}
 		return p.parseValueContent()
 
+	case TokenAssign:
+		return p.errorf("missing key before '='")
+
 	default:
 		return p.errorf("expected identifier, or value, got %s", p.current.Typ)
 	}
 }
 
+// orderedAllowed reports whether an ordered value may follow.
+func (p *Parser) orderedAllowed() bool {
+	return p.config.AllowOrdered && p.config.ForceRoot != RootMap && p.state <= orderedState
+}
+
+// parseBareKey parses a bare identifier as a boolean field set to true.
+func (p *Parser) parseBareKey() bool {
+	p.emit(MapKeyEvent{p.toValue()})
+	p.emit(ValueEvent{BooleanValue{"true"}})
+
+	return p.advance()
+}
+
 // parseBooleanPrefix parses a field with a prefix (^ or !)
 func (p *Parser) parseBooleanPrefix() bool {
 	prefix := p.current.Val // '^' or '!'
@@ -268,8 +469,8 @@ func (p *Parser) parseAssignment() bool {
 
 	// If the next token is a field separator or EOF, it's an empty assignment.
 	if p.isNext(TokenFieldSeparator, TokenEOF) {
-		p.advance()                    // Consume the assignment token.
-		p.emit(ValueEvent{NilValue{}}) // Emit a zero value.
+		p.advance()                     // Consume the assignment token.
+		p.emit(ValueEvent{ZeroValue{}}) // Emit a zero value.
 		return true
 	}
 
@@ -288,10 +489,22 @@ func (p *Parser) parseValueContent() bool {
 		return p.parseDictValue()
 	}
 
+	// A group can only start a list, e.g. (a:1);(b:2).
+	if p.current.Typ == TokenGroupStart {
+		return p.parseListValue()
+	}
+
 	if !p.isValue() {
 		return false
 	}
 
+	// Handle type annotations in front of single values.
+	if p.config.TypeAnnotations && p.current.Typ == TokenIdentifier && p.isNext(TokenPairSeparator) {
+		if matches, ok := typeAnnotations[p.current.Val]; ok {
+			return p.parseAnnotatedValue(p.current.Val, matches)
+		}
+	}
+
 	switch {
 	case p.isNext(TokenPairSeparator):
 		// It's a map, parse as a map starting with the first key.
@@ -309,31 +522,82 @@ func (p *Parser) parseValueContent() bool {
 	}
 }
 
-// parseListValue parses a list starting from a known first value.
+// parseAnnotatedValue parses a single value following a type annotation.
+func (p *Parser) parseAnnotatedValue(typ string, matches func(Token) bool) bool {
+	p.advance() // Consume the type name.
+
+	if !p.advance() || !p.isValue() {
+		return false
+	}
+	if !matches(p.current) {
+		return p.errorf("value %s does not match type %s", p.current.Val, typ)
+	}
+	p.emitValueEvent()
+
+	// Annotated values cannot be continued into a list or map.
+	if p.advance() && p.current.Typ != TokenFieldSeparator && p.current.Typ != TokenEOF {
+		return p.errorf("expected FieldSeparator, got %s", p.current.Typ)
+	}
+	return true
+}
+
+// enter starts a nested list or map, reporting an error if it exceeds the
+// maximum depth. The caller must decrement the depth when leaving it.
+func (p *Parser) enter() bool {
+	// The top-level field list counts as the first level.
+	if p.config.MaxDepth > 0 && p.depth+2 > p.config.MaxDepth {
+		return p.errorf("maximum nesting depth of %d exceeded", p.config.MaxDepth)
+	}
+	p.depth++
+	return true
+}
+
+// parseListValue parses a list starting from a known first value or group.
 func (p *Parser) parseListValue() bool {
+	if !p.enter() {
+		return false
+	}
+	defer func() { p.depth-- }()
+
 	// It's a regular list.
 	p.emit(ListStartEvent{})
-	p.emitValueEvent()
-
-	// Advance to the next token for the list separator.
-	p.advance()
+	if !p.parseListItem() {
+		return false
+	}
 
 	for p.hasToken && p.current.Typ == TokenListSeparator {
-		if !p.advance() || !p.isValue() {
+		if !p.advance() || !p.parseListItem() {
 			return false
 		}
-		p.emitValueEvent()
-
-		// Advance to the next token to check for more separators.
-		p.advance()
 	}
 
 	p.emit(ListEndEvent{})
 	return true
 }
 
+// parseListItem parses a single value or a grouped list or map as a list
+// item and advances past it.
+func (p *Parser) parseListItem() bool {
+	if p.current.Typ == TokenGroupStart {
+		return p.parseGroup()
+	}
+	if !p.isValue() {
+		return false
+	}
+	p.emitValueEvent()
+
+	// Advance to the next token to check for more separators.
+	p.advance()
+	return true
+}
+
 // parseDictValue parses a map starting from a known first key.
 func (p *Parser) parseDictValue() bool {
+	if !p.enter() {
+		return false
+	}
+	defer func() { p.depth-- }()
+
 	p.emit(MapStartEvent{})
 
 	if !p.parseDictEntry() {
@@ -366,25 +630,63 @@ func (p *Parser) parseDictPair() bool {
 	if !p.isValue() {
 		return false
 	}
-	p.emit(MapKeyEvent{p.toValue()})
+	key := p.toValue()
+	if p.config.RejectNonStringKeys {
+		switch key.Type() {
+		case IdentifierValueType, StringValueType, NumberValueType:
+		default:
+			return p.errorf("map key must be identifier, string, or number, got %s", key.Type())
+		}
+	}
+	p.emit(MapKeyEvent{key})
 
 	// Parse the colon between key and value.
 	if !p.advance() || !p.isToken(TokenPairSeparator) {
 		return false
 	}
 
-	// Parse the value.
-	if !p.advance() || !p.isValue() {
+	// Parse the value, which may be a grouped list or map.
+	if !p.advance() {
+		return false
+	}
+	if p.current.Typ == TokenGroupStart {
+		return p.parseGroup()
+	}
+	if !p.isValue() {
 		return false
 	}
 
 	return p.emitValueEvent() && p.advance()
 }
 
+// parseGroup parses a list or map enclosed in parentheses, e.g. (a;b) or
+// (host:x;port:1). A single value in parentheses is a list of one item.
+func (p *Parser) parseGroup() bool {
+	if !p.advance() {
+		return false
+	}
+
+	var ok bool
+	switch {
+	case p.current.Typ == TokenBooleanPrefix:
+		ok = p.parseDictValue()
+	case p.current.Typ == TokenGroupStart:
+		ok = p.parseListValue()
+	case !p.isValue():
+		return false
+	case p.isNext(TokenPairSeparator):
+		ok = p.parseDictValue()
+	default:
+		ok = p.parseListValue()
+	}
+
+	return ok && p.isToken(TokenGroupEnd) && p.advance()
+}
+
 // isValue parses a single value
 func (p *Parser) isValue() bool {
 	switch p.current.Typ {
-	case TokenIdentifier, TokenNumber, TokenString, TokenTrue, TokenFalse, TokenNil:
+	case TokenIdentifier, TokenNumber, TokenString, TokenTrue, TokenFalse, TokenNil, TokenReference:
 		return true
 	default:
 		return p.errorf("expected value, got %s", p.current.Typ)
@@ -395,7 +697,7 @@ func (p *Parser) isValue() bool {
 func ParseTokens(tokens iter.Seq[Token], opts ...ParseOptions) iter.Seq[ParserEvent] {
 	opt := ParseDefaults()
 	if len(opts) > 0 {
-		opt = opts[0]
+		opt = opts[0].WithDefaults()
 	}
 
 	return func(yield func(ParserEvent) bool) {
@@ -414,5 +716,10 @@ func ParseTokens(tokens iter.Seq[Token], opts ...ParseOptions) iter.Seq[ParserEv
 
 // Parse parses the input string and returns an iterator of ParserEvent.
 func Parse(input string, opts ...ParseOptions) iter.Seq[ParserEvent] {
-	return ParseTokens(Lex(input), opts...)
+	opt := ParseDefaults()
+	if len(opts) > 0 {
+		opt = opts[0].WithDefaults()
+	}
+
+	return ParseTokens(Lex(input, opt.Lex), opt)
 }
@@ -1,7 +1,11 @@
 package kaval
 
 import (
+	"fmt"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -32,6 +36,8 @@ func newValue(t ValueType, v string) Value {
 		return BooleanValue{raw: v}
 	case NilValueType:
 		return NilValue{}
+	case ZeroValueType:
+		return ZeroValue{}
 	default:
 		return nil
 	}
@@ -44,6 +50,22 @@ func TestParser(t *testing.T) {
 		expected []ParserEvent
 	}{
 		{"empty input", "", nil},
+		{"whitespace only", "   \n  ", nil},
+		{"tabs and newlines only", "\t\n\r\n\t", nil},
+		{"trailing whitespace", "a=1   \n  ", []ParserEvent{
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "a")},
+			ValueEvent{newValue(NumberValueType, "1")},
+			MapEndEvent{},
+		}},
+		{"leading and interior whitespace", "  \n a = 1 ,\n b = 2", []ParserEvent{
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "a")},
+			ValueEvent{newValue(NumberValueType, "1")},
+			MapKeyEvent{newValue(IdentifierValueType, "b")},
+			ValueEvent{newValue(NumberValueType, "2")},
+			MapEndEvent{},
+		}},
 
 		{"single ordered value", "name", []ParserEvent{
 			ListStartEvent{},
@@ -62,19 +84,19 @@ func TestParser(t *testing.T) {
 			ListEndEvent{},
 		}},
 
-		{"labeled implicit nil single field", "name=", []ParserEvent{
+		{"labeled implicit zero single field", "name=", []ParserEvent{
 			MapStartEvent{},
 			MapKeyEvent{newValue(IdentifierValueType, "name")},
-			ValueEvent{newValue(NilValueType, "")},
+			ValueEvent{newValue(ZeroValueType, "")},
 			MapEndEvent{},
 		}},
 
-		{"labeled implicit nil multiple fields", "a=,b=", []ParserEvent{
+		{"labeled implicit zero multiple fields", "a=,b=", []ParserEvent{
 			MapStartEvent{},
 			MapKeyEvent{newValue(IdentifierValueType, "a")},
-			ValueEvent{newValue(NilValueType, "")},
+			ValueEvent{newValue(ZeroValueType, "")},
 			MapKeyEvent{newValue(IdentifierValueType, "b")},
-			ValueEvent{newValue(NilValueType, "")},
+			ValueEvent{newValue(ZeroValueType, "")},
 			MapEndEvent{},
 		}},
 
@@ -173,6 +195,90 @@ func TestParser(t *testing.T) {
 			MapEndEvent{},
 			MapEndEvent{},
 		}},
+		{"nested map", "settings=db:(host:x;port:1);theme:dark", []ParserEvent{
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "settings")},
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "db")},
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "host")},
+			ValueEvent{newValue(IdentifierValueType, "x")},
+			MapKeyEvent{newValue(IdentifierValueType, "port")},
+			ValueEvent{newValue(NumberValueType, "1")},
+			MapEndEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "theme")},
+			ValueEvent{newValue(IdentifierValueType, "dark")},
+			MapEndEvent{},
+			MapEndEvent{},
+		}},
+		{"two-level nested map", "a=b:(c:(d:1;^e)),f=2", []ParserEvent{
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "a")},
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "b")},
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "c")},
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "d")},
+			ValueEvent{newValue(NumberValueType, "1")},
+			MapKeyEvent{newValue(IdentifierValueType, "e")},
+			ValueEvent{newValue(BooleanValueType, "true")},
+			MapEndEvent{},
+			MapEndEvent{},
+			MapEndEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "f")},
+			ValueEvent{newValue(NumberValueType, "2")},
+			MapEndEvent{},
+		}},
+		{"grouped list in map", "m=tags:(a;b);one:(x)", []ParserEvent{
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "m")},
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "tags")},
+			ListStartEvent{},
+			ValueEvent{newValue(IdentifierValueType, "a")},
+			ValueEvent{newValue(IdentifierValueType, "b")},
+			ListEndEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "one")},
+			ListStartEvent{},
+			ValueEvent{newValue(IdentifierValueType, "x")},
+			ListEndEvent{},
+			MapEndEvent{},
+			MapEndEvent{},
+		}},
+		{"grouped maps in list", "rows=(name:a;port:1);(name:b);c", []ParserEvent{
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "rows")},
+			ListStartEvent{},
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "name")},
+			ValueEvent{newValue(IdentifierValueType, "a")},
+			MapKeyEvent{newValue(IdentifierValueType, "port")},
+			ValueEvent{newValue(NumberValueType, "1")},
+			MapEndEvent{},
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "name")},
+			ValueEvent{newValue(IdentifierValueType, "b")},
+			MapEndEvent{},
+			ValueEvent{newValue(IdentifierValueType, "c")},
+			ListEndEvent{},
+			MapEndEvent{},
+		}},
+		{"grouped lists in grouped list", "m=k:((a;b);c)", []ParserEvent{
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "m")},
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "k")},
+			ListStartEvent{},
+			ListStartEvent{},
+			ValueEvent{newValue(IdentifierValueType, "a")},
+			ValueEvent{newValue(IdentifierValueType, "b")},
+			ListEndEvent{},
+			ValueEvent{newValue(IdentifierValueType, "c")},
+			ListEndEvent{},
+			MapEndEvent{},
+			MapEndEvent{},
+		}},
 		{"mixed value types", `data="hello";123;true;nil`, []ParserEvent{
 			MapStartEvent{},
 			MapKeyEvent{newValue(IdentifierValueType, "data")},
@@ -217,7 +323,7 @@ func TestParser(t *testing.T) {
 		{"single quoted string", "msg='hello world'", []ParserEvent{
 			MapStartEvent{},
 			MapKeyEvent{newValue(IdentifierValueType, "msg")},
-			ValueEvent{newValue(StringValueType, `"hello world"`)},
+			ValueEvent{StringValue{raw: `"hello world"`, src: `'hello world'`}},
 			MapEndEvent{},
 		}},
 		{"empty string", `empty=""`, []ParserEvent{
@@ -226,6 +332,29 @@ func TestParser(t *testing.T) {
 			ValueEvent{newValue(StringValueType, `""`)},
 			MapEndEvent{},
 		}},
+		{"trailing comment", "a=1 # inline note", []ParserEvent{
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "a")},
+			ValueEvent{newValue(NumberValueType, "1")},
+			MapEndEvent{},
+		}},
+		{"trailing comment before next field", "a=x;y # note\n, b=2 # another", []ParserEvent{
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "a")},
+			ListStartEvent{},
+			ValueEvent{newValue(IdentifierValueType, "x")},
+			ValueEvent{newValue(IdentifierValueType, "y")},
+			ListEndEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "b")},
+			ValueEvent{newValue(NumberValueType, "2")},
+			MapEndEvent{},
+		}},
+		{"hash inside string", `a="# not a comment"`, []ParserEvent{
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "a")},
+			ValueEvent{newValue(StringValueType, `"# not a comment"`)},
+			MapEndEvent{},
+		}},
 		{"complex example", "^enabled, name=john, settings=theme:dark;fontSize:14;autoSave:true, tags=dev;prod", []ParserEvent{
 			MapStartEvent{},
 
@@ -288,7 +417,13 @@ func TestParserErrors(t *testing.T) {
 		{"ordered field after labeled field", "name=john,123", "ordered value not allowed here"},
 		{"invalid boolean prefix", "^=true", "expected Identifier, got Assign"},
 		{"invalid boolean prefix with space", "^ =true", "expected Identifier, got Assign"},
-		{"invalid boolean prefix with extra token", "^enabled,=true", "expected identifier, or value, got Assign"},
+		{"invalid boolean prefix with extra token", "^enabled,=true", "missing key before '='"},
+		{"missing key", "=value", "missing key before '='"},
+		{"missing key after field", "a=1,=2", "missing key before '='"},
+		{"unclosed group", "m=k:(a:1", "expected GroupEnd, got EOF"},
+		{"empty group", "m=k:()", "expected value, got GroupEnd"},
+		{"group as ordered value", "(a:1)", "expected identifier, or value, got GroupStart"},
+		{"group as map key", "m=k:1;(a):2", "expected value, got GroupStart"},
 	}
 
 	for _, tt := range tests {
@@ -302,6 +437,80 @@ func TestParserErrors(t *testing.T) {
 	}
 }
 
+func TestParserErrors_MissingKey(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ErrorEvent
+	}{
+		{"=value", ErrorEvent{Pos: Position{Offset: 0, Column: 1}, Msg: "missing key before '='"}},
+		{"a=1, =2", ErrorEvent{Pos: Position{Offset: 5, Column: 6}, Msg: "missing key before '='"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := collectEvents(tt.input)
+			if err == nil {
+				t.Fatalf("Expected error, got none")
+			}
+			if *err != tt.expected {
+				t.Errorf("ParseTokens() error = %#v, want %#v", *err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParserLexerErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected ErrorEvent
+	}{
+		{"leading invalid character", "@", ErrorEvent{Pos: Position{Offset: 0, Column: 1}, Msg: "unexpected character: U+0040 '@'"}},
+		{"invalid character after whitespace", "  @", ErrorEvent{Pos: Position{Offset: 2, Column: 3}, Msg: "unexpected character: U+0040 '@'"}},
+		{"invalid field after valid field", "a,@", ErrorEvent{Pos: Position{Offset: 2, Column: 3}, Msg: "unexpected character: U+0040 '@'"}},
+		{"invalid character as value", "a=@", ErrorEvent{Pos: Position{Offset: 2, Column: 3}, Msg: "unexpected character: U+0040 '@'"}},
+		{"invalid character in list", "a=1;@", ErrorEvent{Pos: Position{Offset: 4, Column: 5}, Msg: "unexpected character: U+0040 '@'"}},
+		{"invalid character in map", "a=k:@", ErrorEvent{Pos: Position{Offset: 4, Column: 5}, Msg: "unexpected character: U+0040 '@'"}},
+		{"invalid character after prefix", "^@", ErrorEvent{Pos: Position{Offset: 1, Column: 2}, Msg: "unexpected character: U+0040 '@'"}},
+		{"unterminated string", `s="unterminated`, ErrorEvent{Pos: Position{Offset: 2, Column: 3}, Msg: "unterminated string"}},
+		{"invalid number after value", "a=1,b=0x", ErrorEvent{Pos: Position{Offset: 8, Column: 9}, Msg: "expected hex digit"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := collectEvents(tt.input)
+			if err == nil {
+				t.Fatalf("Expected error, got none")
+			}
+			if *err != tt.expected {
+				t.Errorf("ParseTokens() error = %#v, want %#v", *err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseTokens_EndOfStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []Token
+	}{
+		{"empty stream", nil},
+		{"stream ending in EOF", []Token{{Typ: TokenEOF, Pos: Position{Offset: 0, Column: 1}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []ParserEvent
+			for event := range ParseTokens(slices.Values(tt.tokens)) {
+				got = append(got, event)
+			}
+			if len(got) != 0 {
+				t.Errorf("ParseTokens() = %#v, want no events", got)
+			}
+		})
+	}
+}
+
 func TestParseOptions(t *testing.T) {
 	tt := []struct {
 		name         string
@@ -313,11 +522,477 @@ func TestParseOptions(t *testing.T) {
 		{
 			name: "disable ordered values",
 			options: ParseOptions{
-				AllowOrdered: false,
+				RejectOrdered: true,
 			},
 			input:       "name,omitempty",
 			wantedError: `ordered value not allowed here`,
 		},
+		{
+			name: "case-insensitive keywords",
+			options: ParseOptions{
+				Lex: LexOptions{CaseInsensitiveKeywords: true},
+			},
+			input: "a=TRUE,b=False,c=Nil",
+			wantedEvents: []ParserEvent{
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "a")},
+				ValueEvent{newValue(BooleanValueType, "true")},
+				MapKeyEvent{newValue(IdentifierValueType, "b")},
+				ValueEvent{newValue(BooleanValueType, "false")},
+				MapKeyEvent{newValue(IdentifierValueType, "c")},
+				ValueEvent{newValue(NilValueType, "")},
+				MapEndEvent{},
+			},
+		},
+		{
+			name: "greedy values",
+			options: ParseOptions{
+				Lex: LexOptions{GreedyValues: true},
+			},
+			input: "q=a=b,n=1",
+			wantedEvents: []ParserEvent{
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "q")},
+				ValueEvent{newValue(IdentifierValueType, "a=b")},
+				MapKeyEvent{newValue(IdentifierValueType, "n")},
+				ValueEvent{newValue(NumberValueType, "1")},
+				MapEndEvent{},
+			},
+		},
+		{
+			name: "multi-rune separators",
+			options: ParseOptions{
+				Lex: LexOptions{FieldSeparator: ",,", PairSeparator: "::"},
+			},
+			input: "a=1,,m=k::v;n::2",
+			wantedEvents: []ParserEvent{
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "a")},
+				ValueEvent{newValue(NumberValueType, "1")},
+				MapKeyEvent{newValue(IdentifierValueType, "m")},
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "k")},
+				ValueEvent{newValue(IdentifierValueType, "v")},
+				MapKeyEvent{newValue(IdentifierValueType, "n")},
+				ValueEvent{newValue(NumberValueType, "2")},
+				MapEndEvent{},
+				MapEndEvent{},
+			},
+		},
+		{
+			name: "matching type annotations",
+			options: ParseOptions{
+				TypeAnnotations: true,
+			},
+			input: "string:x, port=int:8080, ratio=float:1, n=uint:0x10, on=bool:true, s=string:'a', m=k:v",
+			wantedEvents: []ParserEvent{
+				ListStartEvent{},
+				ValueEvent{newValue(IdentifierValueType, "x")},
+				ListEndEvent{},
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "port")},
+				ValueEvent{newValue(NumberValueType, "8080")},
+				MapKeyEvent{newValue(IdentifierValueType, "ratio")},
+				ValueEvent{newValue(NumberValueType, "1")},
+				MapKeyEvent{newValue(IdentifierValueType, "n")},
+				ValueEvent{newValue(NumberValueType, "0x10")},
+				MapKeyEvent{newValue(IdentifierValueType, "on")},
+				ValueEvent{newValue(BooleanValueType, "true")},
+				MapKeyEvent{newValue(IdentifierValueType, "s")},
+				ValueEvent{StringValue{raw: `"a"`, src: `'a'`}},
+				MapKeyEvent{newValue(IdentifierValueType, "m")},
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "k")},
+				ValueEvent{newValue(IdentifierValueType, "v")},
+				MapEndEvent{},
+				MapEndEvent{},
+			},
+		},
+		{
+			name: "mismatching type annotation",
+			options: ParseOptions{
+				TypeAnnotations: true,
+			},
+			input:       "port=int:1.5",
+			wantedError: "value 1.5 does not match type int",
+		},
+		{
+			name: "negative unsigned type annotation",
+			options: ParseOptions{
+				TypeAnnotations: true,
+			},
+			input:       "n=uint:-1",
+			wantedError: "value -1 does not match type uint",
+		},
+		{
+			name: "type annotation on a list",
+			options: ParseOptions{
+				TypeAnnotations: true,
+			},
+			input:       "ports=int:1;2",
+			wantedError: "expected FieldSeparator, got ListSeparator",
+		},
+		{
+			name:  "type annotations disabled",
+			input: "port=int:8080",
+			wantedEvents: []ParserEvent{
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "port")},
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "int")},
+				ValueEvent{newValue(NumberValueType, "8080")},
+				MapEndEvent{},
+				MapEndEvent{},
+			},
+		},
+		{
+			name: "require full consume of valid input",
+			options: ParseOptions{
+				RequireFullConsume: true,
+			},
+			input: "x, ^on, tags=a;b, m=k:v, e=",
+			wantedEvents: []ParserEvent{
+				ListStartEvent{},
+				ValueEvent{newValue(IdentifierValueType, "x")},
+				ListEndEvent{},
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "on")},
+				ValueEvent{newValue(BooleanValueType, "true")},
+				MapKeyEvent{newValue(IdentifierValueType, "tags")},
+				ListStartEvent{},
+				ValueEvent{newValue(IdentifierValueType, "a")},
+				ValueEvent{newValue(IdentifierValueType, "b")},
+				ListEndEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "m")},
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "k")},
+				ValueEvent{newValue(IdentifierValueType, "v")},
+				MapEndEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "e")},
+				ValueEvent{newValue(ZeroValueType, "")},
+				MapEndEvent{},
+			},
+		},
+		{
+			name: "require full consume of trailing value",
+			options: ParseOptions{
+				RequireFullConsume: true,
+			},
+			input:       "name=john doe",
+			wantedError: "unexpected Identifier after field",
+		},
+		{
+			name: "reference values",
+			options: ParseOptions{
+				Lex: LexOptions{ReferencePrefix: '@'},
+			},
+			input: "home=@HOME,dirs=@HOME;'/tmp'",
+			wantedEvents: []ParserEvent{
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "home")},
+				ValueEvent{ReferenceValue{raw: "@HOME"}},
+				MapKeyEvent{newValue(IdentifierValueType, "dirs")},
+				ListStartEvent{},
+				ValueEvent{ReferenceValue{raw: "@HOME"}},
+				ValueEvent{StringValue{raw: `"/tmp"`, src: `'/tmp'`}},
+				ListEndEvent{},
+				MapEndEvent{},
+			},
+		},
+		{
+			name: "bare key as true after labeled field",
+			options: ParseOptions{
+				BareKeyAsTrue: true,
+			},
+			input: "name=john,verbose",
+			wantedEvents: []ParserEvent{
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "name")},
+				ValueEvent{newValue(IdentifierValueType, "john")},
+				MapKeyEvent{newValue(IdentifierValueType, "verbose")},
+				ValueEvent{newValue(BooleanValueType, "true")},
+				MapEndEvent{},
+			},
+		},
+		{
+			name: "bare key as true keeps leading ordered values",
+			options: ParseOptions{
+				BareKeyAsTrue: true,
+			},
+			input: "john,^admin,verbose",
+			wantedEvents: []ParserEvent{
+				ListStartEvent{},
+				ValueEvent{newValue(IdentifierValueType, "john")},
+				ListEndEvent{},
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "admin")},
+				ValueEvent{newValue(BooleanValueType, "true")},
+				MapKeyEvent{newValue(IdentifierValueType, "verbose")},
+				ValueEvent{newValue(BooleanValueType, "true")},
+				MapEndEvent{},
+			},
+		},
+		{
+			name: "bare key as true without ordered values",
+			options: ParseOptions{
+				BareKeyAsTrue: true,
+				RejectOrdered: true,
+			},
+			input: "verbose,debug",
+			wantedEvents: []ParserEvent{
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "verbose")},
+				ValueEvent{newValue(BooleanValueType, "true")},
+				MapKeyEvent{newValue(IdentifierValueType, "debug")},
+				ValueEvent{newValue(BooleanValueType, "true")},
+				MapEndEvent{},
+			},
+		},
+		{
+			name: "bare key as true does not apply to lists",
+			options: ParseOptions{
+				BareKeyAsTrue: true,
+			},
+			input:       "name=john,a;b",
+			wantedError: `ordered value not allowed here`,
+		},
+		{
+			name: "top-level list separator as field separator",
+			options: ParseOptions{
+				TreatTopLevelListSepAsField: true,
+				RequireFullConsume:          true,
+			},
+			input: "a;b;c",
+			wantedEvents: []ParserEvent{
+				ListStartEvent{},
+				ValueEvent{newValue(IdentifierValueType, "a")},
+				ValueEvent{newValue(IdentifierValueType, "b")},
+				ValueEvent{newValue(IdentifierValueType, "c")},
+				ListEndEvent{},
+			},
+		},
+		{
+			name: "top-level list separator does not affect values",
+			options: ParseOptions{
+				TreatTopLevelListSepAsField: true,
+			},
+			input: "a;1,x=b;c",
+			wantedEvents: []ParserEvent{
+				ListStartEvent{},
+				ValueEvent{newValue(IdentifierValueType, "a")},
+				ValueEvent{newValue(NumberValueType, "1")},
+				ListEndEvent{},
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "x")},
+				ListStartEvent{},
+				ValueEvent{newValue(IdentifierValueType, "b")},
+				ValueEvent{newValue(IdentifierValueType, "c")},
+				ListEndEvent{},
+				MapEndEvent{},
+			},
+		},
+		{
+			name: "force list root",
+			options: ParseOptions{
+				ForceRoot: RootList,
+			},
+			input: "a,b;c",
+			wantedEvents: []ParserEvent{
+				ListStartEvent{},
+				ValueEvent{newValue(IdentifierValueType, "a")},
+				ListStartEvent{},
+				ValueEvent{newValue(IdentifierValueType, "b")},
+				ValueEvent{newValue(IdentifierValueType, "c")},
+				ListEndEvent{},
+				ListEndEvent{},
+			},
+		},
+		{
+			name: "error: labeled field with forced list root",
+			options: ParseOptions{
+				ForceRoot: RootList,
+			},
+			input:       "a,b=1",
+			wantedError: `labeled field not allowed here`,
+		},
+		{
+			name: "error: boolean field with forced list root",
+			options: ParseOptions{
+				ForceRoot: RootList,
+			},
+			input:       "^a",
+			wantedError: `labeled field not allowed here`,
+		},
+		{
+			name: "force map root",
+			options: ParseOptions{
+				ForceRoot: RootMap,
+			},
+			input: "a=1,^b",
+			wantedEvents: []ParserEvent{
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "a")},
+				ValueEvent{newValue(NumberValueType, "1")},
+				MapKeyEvent{newValue(IdentifierValueType, "b")},
+				ValueEvent{newValue(BooleanValueType, "true")},
+				MapEndEvent{},
+			},
+		},
+		{
+			name: "error: ordered value with forced map root",
+			options: ParseOptions{
+				ForceRoot: RootMap,
+			},
+			input:       "a,b",
+			wantedError: `ordered value not allowed here`,
+		},
+		{
+			name: "empty input with forced list root",
+			options: ParseOptions{
+				ForceRoot: RootList,
+			},
+			input:        "",
+			wantedEvents: []ParserEvent{ListStartEvent{}, ListEndEvent{}},
+		},
+		{
+			name: "empty input with forced map root",
+			options: ParseOptions{
+				ForceRoot: RootMap,
+			},
+			input:        " # nothing",
+			wantedEvents: []ParserEvent{MapStartEvent{}, MapEndEvent{}},
+		},
+		{
+			name: "max depth alone keeps ordered values",
+			options: ParseOptions{
+				MaxDepth: 5,
+			},
+			input: "a,b=1;2",
+			wantedEvents: []ParserEvent{
+				ListStartEvent{},
+				ValueEvent{newValue(IdentifierValueType, "a")},
+				ListEndEvent{},
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "b")},
+				ListStartEvent{},
+				ValueEvent{newValue(NumberValueType, "1")},
+				ValueEvent{newValue(NumberValueType, "2")},
+				ListEndEvent{},
+				MapEndEvent{},
+			},
+		},
+		{
+			name: "max depth allows single values",
+			options: ParseOptions{
+				MaxDepth: 1,
+			},
+			input: "a=1",
+			wantedEvents: []ParserEvent{
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "a")},
+				ValueEvent{newValue(NumberValueType, "1")},
+				MapEndEvent{},
+			},
+		},
+		{
+			name: "error: list exceeds max depth",
+			options: ParseOptions{
+				MaxDepth: 1,
+			},
+			input:       "a=1;2",
+			wantedError: `maximum nesting depth of 1 exceeded`,
+		},
+		{
+			name: "error: group exceeds max depth",
+			options: ParseOptions{
+				MaxDepth: 2,
+			},
+			input:       "a=b:(c:1)",
+			wantedError: `maximum nesting depth of 2 exceeded`,
+		},
+		{
+			name: "error: map exceeds max depth",
+			options: ParseOptions{
+				MaxDepth: 1,
+			},
+			input:       "k:v",
+			wantedError: `maximum nesting depth of 1 exceeded`,
+		},
+		{
+			name: "reject non-string keys accepts identifiers and numbers",
+			options: ParseOptions{
+				RejectNonStringKeys: true,
+			},
+			input: `m=a:1;0:1;"s":1`,
+			wantedEvents: []ParserEvent{
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "m")},
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "a")},
+				ValueEvent{newValue(NumberValueType, "1")},
+				MapKeyEvent{newValue(NumberValueType, "0")},
+				ValueEvent{newValue(NumberValueType, "1")},
+				MapKeyEvent{newValue(StringValueType, `"s"`)},
+				ValueEvent{newValue(NumberValueType, "1")},
+				MapEndEvent{},
+				MapEndEvent{},
+			},
+		},
+		{
+			name: "error: reject boolean key",
+			options: ParseOptions{
+				RejectNonStringKeys: true,
+			},
+			input:       "true:1",
+			wantedError: `map key must be identifier, string, or number, got boolean`,
+		},
+		{
+			name: "error: reject nil key",
+			options: ParseOptions{
+				RejectNonStringKeys: true,
+			},
+			input:       "m=nil:x",
+			wantedError: `map key must be identifier, string, or number, got nil`,
+		},
+		{
+			name: "error: reject key after first entry",
+			options: ParseOptions{
+				RejectNonStringKeys: true,
+			},
+			input:       "m=a:1;false:2",
+			wantedError: `map key must be identifier, string, or number, got boolean`,
+		},
+		{
+			name: "intern repeated tokens",
+			options: ParseOptions{
+				Intern: true,
+			},
+			input: `a=x,b='x',c=x,d="x"`,
+			wantedEvents: []ParserEvent{
+				MapStartEvent{},
+				MapKeyEvent{newValue(IdentifierValueType, "a")},
+				ValueEvent{newValue(IdentifierValueType, "x")},
+				MapKeyEvent{newValue(IdentifierValueType, "b")},
+				ValueEvent{StringValue{raw: `"x"`, src: `'x'`}},
+				MapKeyEvent{newValue(IdentifierValueType, "c")},
+				ValueEvent{newValue(IdentifierValueType, "x")},
+				MapKeyEvent{newValue(IdentifierValueType, "d")},
+				ValueEvent{newValue(StringValueType, `"x"`)},
+				MapEndEvent{},
+			},
+		},
+		{
+			name:  "non-string keys allowed by default",
+			input: "true:1",
+			wantedEvents: []ParserEvent{
+				ListStartEvent{},
+				MapStartEvent{},
+				MapKeyEvent{newValue(BooleanValueType, "true")},
+				ValueEvent{newValue(NumberValueType, "1")},
+				MapEndEvent{},
+				ListEndEvent{},
+			},
+		},
 	}
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
@@ -339,6 +1014,53 @@ func TestParseOptions(t *testing.T) {
 	}
 }
 
+func TestParseOptions_WithDefaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ParseOptions
+		wanted  bool
+	}{
+		{"zero value", ParseOptions{}, true},
+		{"single option", ParseOptions{MaxDepth: 5}, true},
+		{"reject ordered", ParseOptions{RejectOrdered: true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.options.WithDefaults()
+			if got.AllowOrdered != tt.wanted {
+				t.Errorf("WithDefaults().AllowOrdered = %t, want %t", got.AllowOrdered, tt.wanted)
+			}
+
+			// Other fields are kept.
+			got.AllowOrdered = tt.options.AllowOrdered
+			if !reflect.DeepEqual(got, tt.options) {
+				t.Errorf("WithDefaults() = %+v, want %+v", got, tt.options)
+			}
+		})
+	}
+
+	t.Run("only max depth keeps ordered values", func(t *testing.T) {
+		events, err := collectEvents("a,b,c=1", ParseOptions{MaxDepth: 5})
+		if err != nil {
+			t.Fatalf("collectEvents() error = %v", err)
+		}
+		want := []ParserEvent{
+			ListStartEvent{},
+			ValueEvent{newValue(IdentifierValueType, "a")},
+			ValueEvent{newValue(IdentifierValueType, "b")},
+			ListEndEvent{},
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "c")},
+			ValueEvent{newValue(NumberValueType, "1")},
+			MapEndEvent{},
+		}
+		if !reflect.DeepEqual(events, want) {
+			t.Errorf("collectEvents() = %v, want %v", events, want)
+		}
+	})
+}
+
 // TestParserEventInterface is a silly test that simply calls isParserEvent() on each
 // event type to improve test coverage and doesn't test any functionality.
 func TestParserEventInterface(t *testing.T) {
@@ -366,3 +1088,59 @@ func TestParserEventInterface(t *testing.T) {
 		}
 	})
 }
+
+func BenchmarkParse(b *testing.B) {
+	nested := "m=" + strings.Repeat("k:(", 100) + "v:1" + strings.Repeat(")", 100)
+
+	strs := make([]string, 1000)
+	for i := range strs {
+		strs[i] = fmt.Sprintf(`s%d="value %d, with \"escapes\"\n"`, i, i)
+	}
+
+	items := make([]string, 10_000)
+	for i := range items {
+		items[i] = strconv.Itoa(i)
+	}
+
+	inputs := []struct {
+		name  string
+		input string
+	}{
+		{"flat map", "name=john,age=30,^active,score=9.5,tags=a;b"},
+		{"large list", "list=" + strings.Join(items, ";")},
+		{"nested map", nested},
+		{"strings", strings.Join(strs, ",")},
+	}
+
+	for _, in := range inputs {
+		b.Run(in.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(in.input)))
+			for b.Loop() {
+				for event := range Parse(in.input) {
+					if _, isError := event.(ErrorEvent); isError {
+						b.Fatal(event)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParse_Intern(b *testing.B) {
+	input := strings.Repeat(`host='h',level=info,`, 500)
+
+	for _, intern := range []bool{false, true} {
+		b.Run(fmt.Sprintf("intern=%t", intern), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(input)))
+			for b.Loop() {
+				for event := range Parse(input, ParseOptions{Intern: intern}) {
+					if _, isError := event.(ErrorEvent); isError {
+						b.Fatal(event)
+					}
+				}
+			}
+		})
+	}
+}
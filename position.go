@@ -13,3 +13,18 @@ type Position struct {
 func (p Position) String() string {
 	return fmt.Sprintf("Col %d (Offset %d)", p.Column, p.Offset)
 }
+
+// Before reports whether p is located before q in the input.
+func (p Position) Before(q Position) bool {
+	return p.Offset < q.Offset
+}
+
+// Add returns p advanced by the given number of bytes and columns.
+func (p Position) Add(bytes, cols int) Position {
+	return Position{Offset: p.Offset + bytes, Column: p.Column + cols}
+}
+
+// SpanLen returns the length in bytes of the span from start up to end.
+func SpanLen(start, end Position) int {
+	return end.Offset - start.Offset
+}
@@ -0,0 +1,72 @@
+package kaval
+
+import (
+	"testing"
+)
+
+func TestPosition_Before(t *testing.T) {
+	tests := []struct {
+		name     string
+		p, q     Position
+		expected bool
+	}{
+		{"before", Position{Offset: 1, Column: 2}, Position{Offset: 4, Column: 5}, true},
+		{"after", Position{Offset: 4, Column: 5}, Position{Offset: 1, Column: 2}, false},
+		{"same", Position{Offset: 4, Column: 5}, Position{Offset: 4, Column: 5}, false},
+		{"multi-byte runes", Position{Offset: 3, Column: 2}, Position{Offset: 4, Column: 3}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.Before(tt.q); got != tt.expected {
+				t.Errorf("%v.Before(%v) = %t, want %t", tt.p, tt.q, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPosition_Add(t *testing.T) {
+	tests := []struct {
+		name       string
+		p          Position
+		bytes      int
+		cols       int
+		expected   Position
+		wantLength int
+	}{
+		{"zero", Position{Offset: 2, Column: 3}, 0, 0, Position{Offset: 2, Column: 3}, 0},
+		{"ascii", Position{Offset: 0, Column: 1}, 5, 5, Position{Offset: 5, Column: 6}, 5},
+		{"multi-byte rune", Position{Offset: 4, Column: 5}, 4, 1, Position{Offset: 8, Column: 6}, 4},
+		{"backwards", Position{Offset: 4, Column: 5}, -2, -2, Position{Offset: 2, Column: 3}, -2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.p.Add(tt.bytes, tt.cols)
+			if got != tt.expected {
+				t.Errorf("%v.Add(%d, %d) = %v, want %v", tt.p, tt.bytes, tt.cols, got, tt.expected)
+			}
+			if n := SpanLen(tt.p, got); n != tt.wantLength {
+				t.Errorf("SpanLen(%v, %v) = %d, want %d", tt.p, got, n, tt.wantLength)
+			}
+		})
+	}
+}
+
+func TestSpanLen_Token(t *testing.T) {
+	input := `key="héllo",x=1`
+
+	var tokens []Token
+	for tok := range Lex(input) {
+		tokens = append(tokens, tok)
+	}
+
+	// The string token spans from its start up to the following separator.
+	str, sep := tokens[2], tokens[3]
+	if got := SpanLen(str.Pos, sep.Pos); got != len(str.Val) {
+		t.Errorf("SpanLen() = %d, want %d", got, len(str.Val))
+	}
+	if !str.Pos.Before(sep.Pos) {
+		t.Errorf("%v.Before(%v) = false, want true", str.Pos, sep.Pos)
+	}
+}
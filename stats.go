@@ -0,0 +1,84 @@
+package kaval
+
+// DocumentKind describes which kinds of fields a document holds.
+type DocumentKind int
+
+const (
+	// DocumentEmpty is a document without any fields.
+	DocumentEmpty DocumentKind = iota
+
+	// DocumentList is a document of ordered values only.
+	DocumentList
+
+	// DocumentMap is a document of labeled fields only.
+	DocumentMap
+
+	// DocumentMixed is a document of ordered values followed by labeled
+	// fields.
+	DocumentMixed
+)
+
+// Stats describes the structure of a parsed input.
+type Stats struct {
+	// Fields is the number of top-level fields, ordered and labeled.
+	Fields int
+
+	// MaxDepth is the deepest nesting of lists and maps, counting the
+	// top-level field list as the first level like ParseOptions.MaxDepth.
+	MaxDepth int
+
+	// Values counts the values by their type, including nested lists and
+	// maps but not map keys.
+	Values map[ValueType]int
+
+	// Kind tells whether the document holds ordered or labeled fields.
+	Kind DocumentKind
+}
+
+// ParseStats parses the input and returns statistics about its structure,
+// e.g. to analyze the complexity of configurations.
+func ParseStats(input string, opts ...ParseOptions) (Stats, error) {
+	stats := Stats{Values: make(map[ValueType]int)}
+
+	var depth int // Number of open containers.
+	for event := range Parse(input, opts...) {
+		switch e := event.(type) {
+		case ErrorEvent:
+			return Stats{}, e
+		case ListStartEvent, MapStartEvent:
+			depth++
+			stats.MaxDepth = max(stats.MaxDepth, depth)
+
+			if depth == 2 && stats.Kind == DocumentList {
+				// An ordered list or map field.
+				stats.Fields++
+			}
+
+			_, isList := e.(ListStartEvent)
+			switch {
+			case depth > 1 && isList:
+				stats.Values[ListValueType]++
+			case depth > 1:
+				stats.Values[MapValueType]++
+			case isList:
+				stats.Kind = DocumentList
+			case stats.Kind == DocumentList:
+				stats.Kind = DocumentMixed
+			default:
+				stats.Kind = DocumentMap
+			}
+		case ListEndEvent, MapEndEvent:
+			depth--
+		case MapKeyEvent:
+			if depth == 1 {
+				stats.Fields++
+			}
+		case ValueEvent:
+			if depth == 1 && stats.Kind == DocumentList {
+				stats.Fields++
+			}
+			stats.Values[e.Type()]++
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,64 @@
+package kaval
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStats(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Stats
+	}{
+		{"empty input", "", Stats{Values: map[ValueType]int{}}},
+		{"complex example", "^enabled, name=john, settings=theme:dark;fontSize:14;autoSave:true, tags=dev;prod", Stats{
+			Fields:   4,
+			MaxDepth: 2,
+			Values: map[ValueType]int{
+				BooleanValueType:    2,
+				IdentifierValueType: 4,
+				NumberValueType:     1,
+				ListValueType:       1,
+				MapValueType:        1,
+			},
+			Kind: DocumentMap,
+		}},
+		{"ordered values", `a,1,"s"`, Stats{
+			Fields:   3,
+			MaxDepth: 1,
+			Values:   map[ValueType]int{IdentifierValueType: 1, NumberValueType: 1, StringValueType: 1},
+			Kind:     DocumentList,
+		}},
+		{"mixed with groups", "x;y, a=, s=db:(host:h;port:1)", Stats{
+			Fields:   3,
+			MaxDepth: 3,
+			Values: map[ValueType]int{
+				IdentifierValueType: 3,
+				NumberValueType:     1,
+				ZeroValueType:       1,
+				ListValueType:       1,
+				MapValueType:        2,
+			},
+			Kind: DocumentMixed,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStats(tt.input)
+			if err != nil {
+				t.Fatalf("ParseStats() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseStats() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		if _, err := ParseStats("a=="); err == nil {
+			t.Errorf("ParseStats() expected error, got nil")
+		}
+	})
+}
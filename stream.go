@@ -0,0 +1,90 @@
+package kaval
+
+import (
+	"fmt"
+	"iter"
+)
+
+// TokenStream reads tokens one at a time with a lookahead of one token, e.g.
+// for parsers built on top of Lex.
+//
+// Once the tokens are exhausted, the stream keeps returning the EOF or error
+// token that ended them. Tokens that end without either, or a closed stream,
+// are ended by an EOF token at the end of the last token. Close must be called
+// to release the tokens if the stream is not read to the end.
+type TokenStream struct {
+	next func() (Token, bool)
+	stop func()
+
+	current Token
+	peeked  bool // Set if current holds a token not yet returned by Next.
+}
+
+// NewTokenStream returns a TokenStream reading from tokens.
+func NewTokenStream(tokens iter.Seq[Token]) *TokenStream {
+	next, stop := iter.Pull(tokens)
+	return &TokenStream{
+		next:    next,
+		stop:    stop,
+		current: Token{Typ: TokenEOF},
+	}
+}
+
+// Peek returns the next token without consuming it.
+func (s *TokenStream) Peek() Token {
+	if !s.peeked {
+		if tok, ok := s.next(); ok {
+			s.current = tok
+		} else {
+			s.current = s.eof()
+		}
+		s.peeked = true
+	}
+	return s.current
+}
+
+// eof returns an EOF token at the end of the current token.
+func (s *TokenStream) eof() Token {
+	return Token{Typ: TokenEOF, Pos: s.current.EndPos, EndPos: s.current.EndPos}
+}
+
+// Next consumes and returns the next token.
+func (s *TokenStream) Next() Token {
+	tok := s.Peek()
+	if tok.Typ != TokenEOF && tok.Typ != TokenError {
+		s.peeked = false
+	}
+	return tok
+}
+
+// Expect consumes and returns the next token if it is of the given type.
+// Otherwise the token is left in the stream and an error is returned.
+func (s *TokenStream) Expect(typ TokenType) (Token, error) {
+	tok := s.Peek()
+	switch tok.Typ {
+	case typ:
+		return s.Next(), nil
+	case TokenError:
+		return tok, fmt.Errorf("error at %s: %s", tok.Pos, tok.Val)
+	default:
+		return tok, fmt.Errorf("expected %s at %s, got %s", typ, tok.Pos, tok.Typ)
+	}
+}
+
+// Accept consumes and returns the next token if it is of the given type, and
+// reports whether it did.
+func (s *TokenStream) Accept(typ TokenType) (Token, bool) {
+	if s.Peek().Typ != typ {
+		return Token{}, false
+	}
+	return s.Next(), true
+}
+
+// Close releases the tokens. Afterwards the stream returns EOF tokens.
+func (s *TokenStream) Close() {
+	s.stop()
+	if s.current.Typ != TokenEOF {
+		s.current = s.eof()
+	}
+	s.peeked = true
+}
@@ -0,0 +1,103 @@
+package kaval
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenStream(t *testing.T) {
+	t.Run("expect success", func(t *testing.T) {
+		s := NewTokenStream(Lex("a=1"))
+		defer s.Close()
+
+		for _, typ := range []TokenType{TokenIdentifier, TokenAssign, TokenNumber, TokenEOF} {
+			tok, err := s.Expect(typ)
+			if err != nil {
+				t.Fatalf("Expect(%s) error = %v", typ, err)
+			}
+			if tok.Typ != typ {
+				t.Errorf("Expect(%s) = %v", typ, tok)
+			}
+		}
+
+		// The EOF token is returned again once the tokens are exhausted.
+		if tok := s.Next(); tok.Typ != TokenEOF || tok.Pos.Offset != 3 {
+			t.Errorf("Next() = %v, want EOF at offset 3", tok)
+		}
+	})
+
+	t.Run("expect failure", func(t *testing.T) {
+		s := NewTokenStream(Lex("a=1"))
+		defer s.Close()
+
+		tok, err := s.Expect(TokenNumber)
+		if want := "expected Number at Col 1 (Offset 0), got Identifier"; err == nil || err.Error() != want {
+			t.Errorf("Expect() error = %v, want %q", err, want)
+		}
+		if tok.Typ != TokenIdentifier {
+			t.Errorf("Expect() = %v, want the identifier", tok)
+		}
+
+		// The token is not consumed.
+		if tok := s.Peek(); tok.Typ != TokenIdentifier || tok.Val != "a" {
+			t.Errorf("Peek() = %v, want identifier a", tok)
+		}
+	})
+
+	t.Run("expect lexer error", func(t *testing.T) {
+		s := NewTokenStream(Lex("a=?"))
+		defer s.Close()
+
+		s.Next()
+		s.Next()
+		if _, err := s.Expect(TokenNumber); err == nil {
+			t.Fatalf("Expect() expected error, got nil")
+		}
+		if tok := s.Next(); tok.Typ != TokenError {
+			t.Errorf("Next() = %v, want the error token again", tok)
+		}
+	})
+
+	t.Run("accept", func(t *testing.T) {
+		s := NewTokenStream(Lex("^on,x"))
+		defer s.Close()
+
+		if _, ok := s.Accept(TokenIdentifier); ok {
+			t.Errorf("Accept(Identifier) accepted a prefix")
+		}
+		if tok, ok := s.Accept(TokenBooleanPrefix); !ok || tok.Val != "^" {
+			t.Errorf("Accept(BooleanPrefix) = %v, %t", tok, ok)
+		}
+		if tok, ok := s.Accept(TokenIdentifier); !ok || tok.Val != "on" {
+			t.Errorf("Accept(Identifier) = %v, %t", tok, ok)
+		}
+		if tok := s.Next(); tok.Typ != TokenFieldSeparator {
+			t.Errorf("Next() = %v, want FieldSeparator", tok)
+		}
+	})
+
+	t.Run("close early", func(t *testing.T) {
+		s := NewTokenStream(Lex("a,b,c"))
+		s.Next()
+		s.Close()
+
+		if tok := s.Next(); tok.Typ != TokenEOF || tok.Pos.Offset != 1 {
+			t.Errorf("Next() after Close() = %v, want EOF at offset 1", tok)
+		}
+	})
+
+	t.Run("tokens without EOF", func(t *testing.T) {
+		a := Token{Typ: TokenIdentifier, Val: "a", EndPos: Position{Offset: 1, Column: 2}}
+		s := NewTokenStream(slices.Values([]Token{a}))
+		defer s.Close()
+
+		if tok := s.Next(); tok != a {
+			t.Fatalf("Next() = %v, want %v", tok, a)
+		}
+		for range 2 {
+			if tok := s.Next(); tok.Typ != TokenEOF || tok.Pos != a.EndPos {
+				t.Errorf("Next() = %v, want EOF at %s", tok, a.EndPos)
+			}
+		}
+	})
+}
@@ -22,6 +22,9 @@ const (
 	TokenFieldSeparator // `,`
 	TokenListSeparator  // `;`
 	TokenPairSeparator  // `:`
+	TokenReference      // `@name` with LexOptions.ReferencePrefix set to `@`
+	TokenGroupStart     // `(`
+	TokenGroupEnd       // `)`
 )
 
 func (t TokenType) String() string {
@@ -52,16 +55,37 @@ func (t TokenType) String() string {
 		return "ListSeparator"
 	case TokenPairSeparator:
 		return "PairSeparator"
+	case TokenReference:
+		return "Reference"
+	case TokenGroupStart:
+		return "GroupStart"
+	case TokenGroupEnd:
+		return "GroupEnd"
 	default:
 		return fmt.Sprintf("TokenType(%d)", t)
 	}
 }
 
+// TokenFlags holds additional information about a lexed Token.
+type TokenFlags uint8
+
+const (
+	// TokenFlagSpaceAfter marks a separator or assignment Token directly followed by whitespace.
+	TokenFlagSpaceAfter TokenFlags = 1 << iota
+)
+
+// Has reports whether all the given flags are set.
+func (f TokenFlags) Has(flags TokenFlags) bool {
+	return f&flags == flags
+}
+
 // Token represents a Token produced by the lexer.
 type Token struct {
-	Typ TokenType // Type of this Token.
-	Pos Position  // Starting Position of the Token in the input.
-	Val string    // Token text.
+	Typ    TokenType  // Type of this Token.
+	Pos    Position   // Starting Position of the Token in the input.
+	EndPos Position   // Position following the Token, where lexing resumes.
+	Val    string     // Token text.
+	Flags  TokenFlags // Additional information about the Token.
 }
 
 func (t Token) String() string {
@@ -23,6 +23,9 @@ func TestTokenTypeString(t *testing.T) {
 		{TokenFieldSeparator, "FieldSeparator"},
 		{TokenListSeparator, "ListSeparator"},
 		{TokenPairSeparator, "PairSeparator"},
+		{TokenReference, "Reference"},
+		{TokenGroupStart, "GroupStart"},
+		{TokenGroupEnd, "GroupEnd"},
 
 		// The silly part: test invalid token types
 		{TokenType(9999), "TokenType(9999)"},
@@ -56,10 +59,10 @@ func TestTokenString(t *testing.T) {
 
 	// Test some silly tokens for fun.
 	sillyTokens := []Token{
-		{TokenError, Position{Offset: 0, Column: 1}, "oopsie!"},
-		{TokenString, Position{Offset: 123, Column: 456}, "\"escaped\\quotes\""},
-		{TokenNumber, Position{Offset: 42, Column: 42}, "0xDEADBEEF"},
-		{TokenType(9999), Position{Offset: 9999, Column: 9999}, "👽"},
+		{Typ: TokenError, Pos: Position{Offset: 0, Column: 1}, Val: "oopsie!"},
+		{Typ: TokenString, Pos: Position{Offset: 123, Column: 456}, Val: "\"escaped\\quotes\""},
+		{Typ: TokenNumber, Pos: Position{Offset: 42, Column: 42}, Val: "0xDEADBEEF"},
+		{Typ: TokenType(9999), Pos: Position{Offset: 9999, Column: 9999}, Val: "👽"},
 	}
 
 	for i, token := range sillyTokens {
@@ -71,3 +74,15 @@ func TestTokenString(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenFlagsHas(t *testing.T) {
+	var flags TokenFlags
+	if flags.Has(TokenFlagSpaceAfter) {
+		t.Errorf("expected flag to be unset")
+	}
+
+	flags |= TokenFlagSpaceAfter
+	if !flags.Has(TokenFlagSpaceAfter) {
+		t.Errorf("expected flag to be set")
+	}
+}
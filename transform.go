@@ -0,0 +1,128 @@
+package kaval
+
+import (
+	"iter"
+)
+
+// Transform returns an iterator that yields events with every value replaced
+// by the result of fn.
+//
+// fn receives the key the value belongs to: the nearest enclosing map key,
+// which for list items is the key the list is assigned to. Ordered values
+// outside any map are passed an empty key. All other events are passed
+// through unchanged.
+func Transform(events iter.Seq[ParserEvent], fn func(key string, v Value) Value) iter.Seq[ParserEvent] {
+	return func(yield func(ParserEvent) bool) {
+		var (
+			key   string
+			stack []string // Key in effect when each open container started.
+		)
+
+		for event := range events {
+			switch e := event.(type) {
+			case ListStartEvent, MapStartEvent:
+				stack = append(stack, key)
+			case ListEndEvent, MapEndEvent:
+				if n := len(stack); n > 0 {
+					key, stack = stack[n-1], stack[:n-1]
+				}
+			case MapKeyEvent:
+				key = textOf(e.Value)
+			case ValueEvent:
+				event = ValueEvent{fn(key, e.Value)}
+			}
+
+			if !yield(event) {
+				return
+			}
+		}
+	}
+}
+
+// Filter returns an iterator that yields only the labeled fields whose key
+// satisfies keep, e.g. to project a document to selected fields with Encode.
+//
+// The value of a dropped field is skipped entirely, including nested lists
+// and maps. Ordered values and all other events are passed through.
+func Filter(events iter.Seq[ParserEvent], keep func(key string) bool) iter.Seq[ParserEvent] {
+	return func(yield func(ParserEvent) bool) {
+		var (
+			depth    int  // Number of open containers.
+			dropping bool // Set while skipping the value of a dropped field.
+			skipped  int  // Depth at which the dropped field started.
+		)
+
+		for event := range events {
+			switch e := event.(type) {
+			case ListStartEvent, MapStartEvent:
+				depth++
+			case ListEndEvent, MapEndEvent:
+				depth--
+			case MapKeyEvent:
+				if depth == 1 && !keep(textOf(e.Value)) {
+					dropping, skipped = true, depth
+					continue
+				}
+			}
+
+			if dropping {
+				// The value ends with a scalar at the field's depth, or with the
+				// end of a container that returns to it.
+				if depth == skipped {
+					dropping = false
+				}
+				if _, isError := event.(ErrorEvent); !isError {
+					continue
+				}
+			}
+
+			if !yield(event) {
+				return
+			}
+		}
+	}
+}
+
+// FieldEvents parses the input and yields the events of each top-level field
+// as one slice: a key event followed by the events of its value, or the event
+// of a single ordered value. The events that open and close the document are
+// not included.
+//
+// An error is yielded together with the events of the incomplete field and
+// ends the iteration.
+func FieldEvents(input string, opts ...ParseOptions) iter.Seq[[]ParserEvent] {
+	return func(yield func([]ParserEvent) bool) {
+		var (
+			depth int // Number of open containers.
+			field []ParserEvent
+		)
+
+		for event := range Parse(input, opts...) {
+			switch event.(type) {
+			case ListStartEvent, MapStartEvent:
+				depth++
+				if depth == 1 {
+					continue
+				}
+			case ListEndEvent, MapEndEvent:
+				depth--
+				if depth == 0 {
+					continue
+				}
+			case ErrorEvent:
+				yield(append(field, event))
+				return
+			}
+
+			field = append(field, event)
+
+			// A field is complete once its value ends at the top level.
+			if _, isKey := event.(MapKeyEvent); depth == 1 && !isKey {
+				if !yield(field) {
+					return
+				}
+				field = nil
+			}
+		}
+	}
+}
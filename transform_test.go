@@ -0,0 +1,171 @@
+package kaval
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	redact := func(key string, v Value) Value {
+		if key == "password" {
+			return StringValue{raw: `"***"`}
+		}
+		return v
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []ParserEvent
+	}{
+		{"redact labeled field", "user=john,password=secret", []ParserEvent{
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "user")},
+			ValueEvent{newValue(IdentifierValueType, "john")},
+			MapKeyEvent{newValue(IdentifierValueType, "password")},
+			ValueEvent{newValue(StringValueType, `"***"`)},
+			MapEndEvent{},
+		}},
+		{"redact list items", "password=a;b,user=john", []ParserEvent{
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "password")},
+			ListStartEvent{},
+			ValueEvent{newValue(StringValueType, `"***"`)},
+			ValueEvent{newValue(StringValueType, `"***"`)},
+			ListEndEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "user")},
+			ValueEvent{newValue(IdentifierValueType, "john")},
+			MapEndEvent{},
+		}},
+		{"redact nested map entry", "db=password:secret;host:localhost", []ParserEvent{
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "db")},
+			MapStartEvent{},
+			MapKeyEvent{newValue(IdentifierValueType, "password")},
+			ValueEvent{newValue(StringValueType, `"***"`)},
+			MapKeyEvent{newValue(IdentifierValueType, "host")},
+			ValueEvent{newValue(IdentifierValueType, "localhost")},
+			MapEndEvent{},
+			MapEndEvent{},
+		}},
+		{"ordered values have no key", "password,secret", []ParserEvent{
+			ListStartEvent{},
+			ValueEvent{newValue(IdentifierValueType, "password")},
+			ValueEvent{newValue(IdentifierValueType, "secret")},
+			ListEndEvent{},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []ParserEvent
+			for event := range Transform(Parse(tt.input), redact) {
+				got = append(got, event)
+			}
+
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Transform() = %#v, want %#v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		keys     []string
+		expected string
+	}{
+		{"keep single field", "name=x,age=30,tags=a;b", []string{"name"}, "name=x"},
+		{"drop list", "tags=a;b,name=x", []string{"name"}, "name=x"},
+		{"drop map", "db=host:h;port:1,name=x,m=k:v", []string{"name", "m"}, "name=x,m=k:v"},
+		{"keep none", "name=x,age=30", nil, ""},
+		{"ordered values pass through", "a,b", nil, "a,b"},
+		{"boolean fields", "^on,!off,name=x", []string{"off"}, "off=false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keep := func(key string) bool { return slices.Contains(tt.keys, key) }
+
+			got, err := Encode(Filter(Parse(tt.input), keep))
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Encode(Filter()) = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("errors pass through", func(t *testing.T) {
+		var got []ParserEvent
+		for event := range Filter(Parse("a=1,b=="), func(string) bool { return false }) {
+			got = append(got, event)
+		}
+
+		want := []ParserEvent{MapStartEvent{}}
+		if len(got) != 2 || !reflect.DeepEqual(got[:1], want) {
+			t.Fatalf("Filter() = %#v", got)
+		}
+		if _, isError := got[1].(ErrorEvent); !isError {
+			t.Errorf("Filter() last event = %#v, want ErrorEvent", got[1])
+		}
+	})
+}
+
+func TestFieldEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected [][]string
+	}{
+		{"labeled fields", "name=x,tags=a;b", [][]string{
+			{`MapKey identifier "name"`, `Value identifier "x"`},
+			{`MapKey identifier "tags"`, "ListStart", `Value identifier "a"`, `Value identifier "b"`, "ListEnd"},
+		}},
+		{"ordered and labeled fields", "x,m=k:v", [][]string{
+			{`Value identifier "x"`},
+			{`MapKey identifier "m"`, "MapStart", `MapKey identifier "k"`, `Value identifier "v"`, "MapEnd"},
+		}},
+		{"nested group", "s=db:(p:1;q:2)", [][]string{
+			{`MapKey identifier "s"`, "MapStart", `MapKey identifier "db"`, "MapStart", `MapKey identifier "p"`, `Value number "1"`, `MapKey identifier "q"`, `Value number "2"`, "MapEnd", "MapEnd"},
+		}},
+		{"empty input", "", nil},
+		{"error", "a=1,b==", [][]string{
+			{`MapKey identifier "a"`, `Value number "1"`},
+			{`MapKey identifier "b"`, "kaval.ErrorEvent"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]string
+			for field := range FieldEvents(tt.input) {
+				var lines []string
+				for _, event := range field {
+					lines = append(lines, dumpEvent(event))
+				}
+				got = append(got, lines)
+			}
+
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("FieldEvents(%q) =\n  got:  %q\n  want: %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("stop early", func(t *testing.T) {
+		var n int
+		for range FieldEvents("a=1,b=2,c=3") {
+			if n++; n == 2 {
+				break
+			}
+		}
+		if n != 2 {
+			t.Errorf("FieldEvents() yielded %d fields, want 2", n)
+		}
+	})
+}
@@ -1,10 +1,14 @@
 package kaval
 
 import (
+	"cmp"
+	"encoding/base64"
 	"fmt"
-	"math"
+	"iter"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 type ValueType int
@@ -16,6 +20,11 @@ const (
 	NumberValueType
 	IdentifierValueType
 	StringValueType
+	ListValueType
+	MapValueType
+	ReferenceValueType
+	ZeroValueType
+	UndefinedValueType
 )
 
 // GoString returns the Go string representation of the ValueType.
@@ -33,6 +42,16 @@ func (vt ValueType) GoString() string {
 		return "StringValueType"
 	case IdentifierValueType:
 		return "IdentifierValueType"
+	case ListValueType:
+		return "ListValueType"
+	case MapValueType:
+		return "MapValueType"
+	case ReferenceValueType:
+		return "ReferenceValueType"
+	case ZeroValueType:
+		return "ZeroValueType"
+	case UndefinedValueType:
+		return "UndefinedValueType"
 	default:
 		return fmt.Sprintf("ValueType(%d)", vt)
 	}
@@ -53,18 +72,46 @@ func (vt ValueType) String() string {
 		return "string"
 	case IdentifierValueType:
 		return "identifier"
+	case ListValueType:
+		return "list"
+	case MapValueType:
+		return "map"
+	case ReferenceValueType:
+		return "reference"
+	case ZeroValueType:
+		return "zero"
+	case UndefinedValueType:
+		return "undefined"
 	default:
 		return fmt.Sprintf("ValueType(%d)", vt)
 	}
 }
 
+// Friendly returns a user-facing name of the ValueType for messages built by
+// callers, e.g. "text" for both strings and identifiers. Conversion errors
+// keep using String.
+func (vt ValueType) Friendly() string {
+	switch vt {
+	case InvalidValueType:
+		return "invalid value"
+	case StringValueType, IdentifierValueType:
+		return "text"
+	case ZeroValueType:
+		return "empty value"
+	case UndefinedValueType:
+		return "missing value"
+	default:
+		return vt.String()
+	}
+}
+
 // Value represents a plainfields value.
 type Value interface {
 	Type() ValueType
 	Raw() string
 }
 
-// NilValue represents a nil or zero value.
+// NilValue represents the nil keyword.
 type NilValue struct{}
 
 func (v NilValue) Type() ValueType { return NilValueType }
@@ -72,6 +119,26 @@ func (v NilValue) Raw() string     { return "nil" }
 func (v NilValue) String() string  { return fmt.Sprintf("%s (%s)", v.Raw(), v.Type()) }
 func (v NilValue) IsNil() bool     { return true }
 
+// ZeroValue represents the absent value of an empty assignment, e.g. name=.
+type ZeroValue struct{}
+
+func (v ZeroValue) Type() ValueType { return ZeroValueType }
+func (v ZeroValue) Raw() string     { return "" }
+func (v ZeroValue) String() string  { return fmt.Sprintf("%s (%s)", v.Raw(), v.Type()) }
+func (v ZeroValue) IsNil() bool     { return true }
+
+// UndefinedValue represents a field that is not present in the input, e.g.
+// as returned by Document.Get for a missing key.
+//
+// Like ZeroValue it is reported as nil by IsNil and IsZero, compare the
+// Type to tell a missing field from an empty assignment.
+type UndefinedValue struct{}
+
+func (v UndefinedValue) Type() ValueType { return UndefinedValueType }
+func (v UndefinedValue) Raw() string     { return "" }
+func (v UndefinedValue) String() string  { return fmt.Sprintf("%s (%s)", v.Raw(), v.Type()) }
+func (v UndefinedValue) IsNil() bool     { return true }
+
 // BooleanValue represents a boolean value.
 type BooleanValue struct{ raw string }
 
@@ -106,13 +173,26 @@ func (v NumberValue) IsNil() bool {
 }
 func (v NumberValue) ToFloat() (float64, error) {
 	s := strings.ReplaceAll(v.raw, "_", "")
+
+	// The base prefix follows the sign, so the sign is applied after parsing.
+	sign := 1.0
+	if len(s) > 0 && isNumericSign(rune(s[0])) {
+		if s[0] == '-' {
+			sign = -1
+		}
+		s = s[1:]
+	}
+
+	f, err := parseUnsignedFloat(s)
+	return sign * f, err
+}
+
+// parseUnsignedFloat parses an unsigned decimal, hex, octal or binary number.
+func parseUnsignedFloat(s string) (float64, error) {
 	if len(s) > 2 && s[0] == '0' {
 		switch s[1] {
 		case 'x', 'X':
-			if strings.ContainsAny(s, "pP") {
-				return strconv.ParseFloat(s, 64)
-			}
-			if strings.Contains(s, ".") {
+			if strings.ContainsAny(s, ".pP") {
 				return parseHexFloat(s)
 			}
 			v, err := strconv.ParseUint(s[2:], 16, 64)
@@ -128,69 +208,305 @@ func (v NumberValue) ToFloat() (float64, error) {
 	return strconv.ParseFloat(s, 64)
 }
 
+// parseHexFloat parses an unsigned hexadecimal floating-point number with an
+// optional fraction and an optional binary exponent.
 func parseHexFloat(s string) (float64, error) {
-	// Split into mantissa and exponent
-	mantissaStr := s[2:]
+	// strconv requires the binary exponent for hexadecimal floats, a missing
+	// exponent is equivalent to scaling by 2^0.
+	if !strings.ContainsAny(s, "pP") {
+		s += "p0"
+	}
+	return strconv.ParseFloat(s, 64)
+}
 
-	dotIdx := strings.IndexByte(mantissaStr, '.')
-	intPart := mantissaStr[:dotIdx]
-	fracPart := mantissaStr[dotIdx+1:]
+// IdentifierValue represents an identifier value.
+type IdentifierValue struct{ raw string }
 
-	var mantissa float64
+func (v IdentifierValue) Type() ValueType { return IdentifierValueType }
+func (v IdentifierValue) Raw() string     { return v.raw }
+func (v IdentifierValue) String() string  { return fmt.Sprintf("%s (%s)", v.Raw(), v.Type()) }
+func (v IdentifierValue) ToString() (string, error) {
+	if !strings.ContainsRune(v.raw, '\\') {
+		return v.raw, nil
+	}
+	return unescapeIdentifier(v.raw)
+}
 
-	// Parse integer part
-	if intPart != "" {
-		v, err := strconv.ParseUint(intPart, 16, 64)
+// unescapeIdentifier decodes the escape sequences of an identifier.
+//
+// Escapes known to strings such as \n are decoded the same way, any other
+// escaped character stands for itself, e.g. \, for a comma.
+func unescapeIdentifier(s string) (string, error) {
+	var sb strings.Builder
+	for len(s) > 0 {
+		r, multibyte, tail, err := strconv.UnquoteChar(s, 0)
 		if err != nil {
-			return 0, err
+			if len(s) < 2 || s[0] != '\\' {
+				return "", fmt.Errorf("invalid escape sequence in identifier: %q", s)
+			}
+
+			// Not a string escape, the escaped character stands for itself.
+			var w int
+			r, w = utf8.DecodeRuneInString(s[1:])
+			multibyte, tail = r >= utf8.RuneSelf, s[1+w:]
 		}
-		mantissa = float64(v)
-	}
 
-	// Parse fractional part
-	if fracPart != "" {
-		v, err := strconv.ParseUint(fracPart, 16, 64)
-		if err != nil {
-			return 0, err
+		if multibyte {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte(byte(r))
 		}
-		mantissa += float64(v) / math.Pow(16, float64(len(fracPart)))
+		s = tail
 	}
-
-	return mantissa, nil
+	return sb.String(), nil
 }
 
-// IdentifierValue represents an identifier value.
-type IdentifierValue struct{ raw string }
+// ReferenceValue represents a reference to another value by its name.
+type ReferenceValue struct{ raw string }
 
-func (v IdentifierValue) Type() ValueType { return IdentifierValueType }
-func (v IdentifierValue) Raw() string     { return v.raw }
-func (v IdentifierValue) String() string  { return fmt.Sprintf("%s (%s)", v.Raw(), v.Type()) }
-func (v IdentifierValue) ToString() (string, error) {
-	return v.raw, nil
+func (v ReferenceValue) Type() ValueType { return ReferenceValueType }
+func (v ReferenceValue) Raw() string     { return v.raw }
+func (v ReferenceValue) String() string  { return fmt.Sprintf("%s (%s)", v.Raw(), v.Type()) }
+
+// Name returns the referenced name without the reference prefix.
+func (v ReferenceValue) Name() string {
+	_, w := utf8.DecodeRuneInString(v.raw)
+	return v.raw[w:]
 }
 
 // StringValue represents a string value.
-type StringValue struct{ raw string }
+type StringValue struct {
+	raw string // Quoted text normalized to double quotes.
+	src string // Quoted text as written in the input if it differs from raw.
+}
 
 func (v StringValue) Type() ValueType { return StringValueType }
 func (v StringValue) Raw() string     { return v.raw }
 func (v StringValue) String() string  { return fmt.Sprintf("%s (%s)", v.Raw(), v.Type()) }
-func (v StringValue) IsNil() bool     { return len(v.raw) <= 2 }
+
+// IsNil reports whether the string is empty, i.e. its raw text holds only the
+// quotes. Strings of whitespace are not empty.
+func (v StringValue) IsNil() bool { return len(v.raw) <= 2 }
+
 func (v StringValue) ToString() (string, error) {
 	return strconv.Unquote(v.raw)
 }
 
+// Source returns the string with its quotes and escapes as written in the input.
+func (v StringValue) Source() string {
+	if v.src != "" {
+		return v.src
+	}
+	return v.raw
+}
+
+// BytesValue represents binary data written as a base64 encoded string.
+//
+// It is read back as a StringValue whose bytes are decoded with ToBytes, so
+// Type reports StringValueType, like the value it reads back as. Use
+// As[BytesValue] to tell built bytes apart from strings.
+type BytesValue struct{ data []byte }
+
+// NewBytes returns a BytesValue holding b.
+func NewBytes(b []byte) BytesValue {
+	return BytesValue{data: b}
+}
+
+func (v BytesValue) Type() ValueType           { return StringValueType }
+func (v BytesValue) Raw() string               { return `"` + base64.StdEncoding.EncodeToString(v.data) + `"` }
+func (v BytesValue) String() string            { return fmt.Sprintf("%s (%s)", v.Raw(), v.Type()) }
+func (v BytesValue) IsNil() bool               { return len(v.data) == 0 }
+func (v BytesValue) ToBytes() ([]byte, error)  { return v.data, nil }
+func (v BytesValue) ToString() (string, error) { return base64.StdEncoding.EncodeToString(v.data), nil }
+
+// ListValue represents a list of values.
+type ListValue struct{ items []Value }
+
+func (v ListValue) Type() ValueType { return ListValueType }
+func (v ListValue) String() string  { return fmt.Sprintf("%s (%s)", v.Raw(), v.Type()) }
+func (v ListValue) IsNil() bool     { return len(v.items) == 0 }
+func (v ListValue) Len() int        { return len(v.items) }
+
+// Raw returns the items in list notation.
+func (v ListValue) Raw() string {
+	items := make([]string, len(v.items))
+	for i, item := range v.items {
+		items[i] = group(item, item.Raw())
+	}
+	return strings.Join(items, ";")
+}
+
+// Source returns the source of the items joined by the canonical list
+// separator. Spacing and custom separators of the input are not kept.
+func (v ListValue) Source() string {
+	items := make([]string, len(v.items))
+	for i, item := range v.items {
+		items[i] = group(item, Source(item))
+	}
+	return strings.Join(items, ";")
+}
+
+// Items returns the items of the list. The returned slice must not be modified.
+func (v ListValue) Items() []Value {
+	return v.items
+}
+
+// Equal reports whether other is a list with equal items in the same order.
+func (v ListValue) Equal(other Value) bool {
+	o, ok := As[ListValue](other)
+	if !ok || len(v.items) != len(o.items) {
+		return false
+	}
+	for i, item := range v.items {
+		if !Equal(item, o.items[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Join converts every item to a string and joins them with sep. It returns
+// an error if an item is not string-convertible.
+func (v ListValue) Join(sep string) (string, error) {
+	items := make([]string, len(v.items))
+	for i, item := range v.items {
+		s, err := ToString(item)
+		if err != nil {
+			return "", fmt.Errorf("item %d: %w", i, err)
+		}
+		items[i] = s
+	}
+	return strings.Join(items, sep), nil
+}
+
+// MapValue represents a map of key-value pairs in their original order.
+type MapValue struct {
+	keys   []Value
+	values []Value
+}
+
+func (v MapValue) Type() ValueType { return MapValueType }
+func (v MapValue) String() string  { return fmt.Sprintf("%s (%s)", v.Raw(), v.Type()) }
+func (v MapValue) IsNil() bool     { return len(v.keys) == 0 }
+func (v MapValue) Len() int        { return len(v.keys) }
+
+// Raw returns the entries in map notation.
+func (v MapValue) Raw() string {
+	entries := make([]string, len(v.keys))
+	for i, key := range v.keys {
+		entries[i] = key.Raw() + ":" + group(v.values[i], v.values[i].Raw())
+	}
+	return strings.Join(entries, ";")
+}
+
+// Source returns the source of the entries joined by the canonical
+// separators. Spacing and custom separators of the input are not kept.
+func (v MapValue) Source() string {
+	entries := make([]string, len(v.keys))
+	for i, key := range v.keys {
+		entries[i] = Source(key) + ":" + group(v.values[i], Source(v.values[i]))
+	}
+	return strings.Join(entries, ";")
+}
+
+// group encloses the text of a nested list or map value in parentheses.
+func group(v Value, text string) string {
+	switch v.(type) {
+	case ListValue, MapValue:
+		return "(" + text + ")"
+	default:
+		return text
+	}
+}
+
+// Keys returns the keys of the map. The returned slice must not be modified.
+func (v MapValue) Keys() []Value {
+	return v.keys
+}
+
+// Get returns the value of the first entry whose decoded key equals key.
+// Numeric keys are matched by their canonical decimal form, e.g. 255 for 0xFF.
+func (v MapValue) Get(key string) (Value, bool) {
+	for i, k := range v.keys {
+		if keyString(k) == key {
+			return v.values[i], true
+		}
+	}
+	return nil, false
+}
+
+// ToMap returns the entries by their decoded key. Numeric keys are
+// normalized to their canonical decimal form, e.g. 255 for 0xFF. Later
+// entries replace earlier ones with the same key.
+func (v MapValue) ToMap() map[string]Value {
+	m := make(map[string]Value, len(v.keys))
+	for i, key := range v.keys {
+		m[keyString(key)] = v.values[i]
+	}
+	return m
+}
+
+// Entries returns an iterator over the entries of the map in their original order.
+func (v MapValue) Entries() iter.Seq2[Value, Value] {
+	return func(yield func(Value, Value) bool) {
+		for i, key := range v.keys {
+			if !yield(key, v.values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Equal reports whether other is a map with equal entries, regardless of
+// their order. Each entry of other is matched at most once, so duplicate
+// keys must occur with equal values as often in both maps.
+func (v MapValue) Equal(other Value) bool {
+	o, ok := As[MapValue](other)
+	if !ok || len(v.keys) != len(o.keys) {
+		return false
+	}
+	used := make([]bool, len(o.keys))
+	for i, key := range v.keys {
+		j := -1
+		for n, k := range o.keys {
+			if !used[n] && Equal(key, k) && Equal(v.values[i], o.values[n]) {
+				j = n
+				break
+			}
+		}
+		if j < 0 {
+			return false
+		}
+		used[j] = true
+	}
+	return true
+}
+
+// set appends an entry to the map.
+func (v *MapValue) set(key, value Value) {
+	v.keys = append(v.keys, key)
+	v.values = append(v.values, value)
+}
+
 // valueFromToken converts a token to a Value.
 func valueFromToken(token Token) Value {
 	switch token.Typ {
 	case TokenString:
-		return StringValue{raw: `"` + token.Val[1:len(token.Val)-1] + `"`}
+		v := StringValue{raw: doubleQuote(token.Val[1 : len(token.Val)-1])}
+		if v.raw != token.Val {
+			v.src = token.Val
+		}
+		return v
 	case TokenNumber:
 		return NumberValue{raw: token.Val}
 	case TokenIdentifier:
 		return IdentifierValue{raw: token.Val}
-	case TokenFalse, TokenTrue:
-		return BooleanValue{raw: token.Val}
+	case TokenReference:
+		return ReferenceValue{raw: token.Val}
+	case TokenTrue:
+		return BooleanValue{raw: "true"}
+	case TokenFalse:
+		return BooleanValue{raw: "false"}
 	case TokenNil:
 		return NilValue{}
 	default:
@@ -198,6 +514,34 @@ func valueFromToken(token Token) Value {
 	}
 }
 
+// doubleQuote returns the content of a quoted string enclosed in double
+// quotes. Escaped single quotes are unescaped and double quotes are escaped,
+// so both quote styles decode the same escapes with strconv.Unquote.
+func doubleQuote(s string) string {
+	if !strings.ContainsAny(s, `\"`) {
+		return `"` + s + `"`
+	}
+
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == '\'':
+			sb.WriteByte('\'')
+			i++
+		case s[i] == '\\' && i+1 < len(s):
+			sb.WriteString(s[i : i+2])
+			i++
+		case s[i] == '"':
+			sb.WriteString(`\"`)
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
 // As attempts to convert a value to a given type.
 func As[T any](v any) (val T, ok bool) {
 	if val, ok = v.(T); ok {
@@ -212,6 +556,115 @@ func As[T any](v any) (val T, ok bool) {
 	return zero, false
 }
 
+// Equal reports whether two values are equal.
+//
+// Values providing an Equal method are compared with it, all other values are
+// equal if they are of the same type and have the same raw text.
+func Equal(a, b Value) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if eq, ok := As[interface{ Equal(Value) bool }](a); ok {
+		return eq.Equal(b)
+	}
+	return a.Type() == b.Type() && a.Raw() == b.Raw()
+}
+
+// Compare returns -1, 0 or +1 depending on whether a sorts before, equal to
+// or after b.
+//
+// Numbers are compared by their numeric value, exactly for integers, strings
+// and identifiers by their decoded text and booleans with false before true.
+// Values of different kinds sort as undefined, zero, nil, booleans, numbers,
+// text and then all other types by their ValueType, which are compared by
+// their raw text.
+func Compare(a, b Value) int {
+	if c := cmp.Compare(compareRank(a), compareRank(b)); c != 0 {
+		return c
+	}
+
+	switch a.Type() {
+	case UndefinedValueType, ZeroValueType, NilValueType:
+		return 0
+	case NumberValueType:
+		if c, ok := compareIntegers(a, b); ok {
+			return c
+		}
+		fa, errA := ToFloat(a)
+		fb, errB := ToFloat(b)
+		if errA == nil && errB == nil {
+			return cmp.Compare(fa, fb)
+		}
+	case StringValueType, IdentifierValueType:
+		return strings.Compare(textOf(a), textOf(b))
+	}
+	return strings.Compare(a.Raw(), b.Raw())
+}
+
+// compareIntegers compares two numbers exactly if both are integers, which
+// a float64 cannot hold beyond 2^53, and reports whether they were.
+func compareIntegers(a, b Value) (int, bool) {
+	ia, errIA := ToInt(a)
+	ib, errIB := ToInt(b)
+	if errIA == nil && errIB == nil {
+		return cmp.Compare(ia, ib), true
+	}
+	ua, errUA := ToUint(a)
+	ub, errUB := ToUint(b)
+	switch {
+	case errUA == nil && errUB == nil:
+		return cmp.Compare(ua, ub), true
+	case errIA == nil && errUB == nil:
+		// a is negative and b is above the range of int64.
+		return -1, true
+	case errUA == nil && errIB == nil:
+		return +1, true
+	}
+	return 0, false
+}
+
+// compareRank returns the position of the kind of a Value in the order used
+// by Compare.
+func compareRank(v Value) int {
+	switch t := v.Type(); t {
+	case UndefinedValueType:
+		return 0
+	case ZeroValueType:
+		return 1
+	case NilValueType:
+		return 2
+	case BooleanValueType:
+		return 3
+	case NumberValueType:
+		return 4
+	case StringValueType, IdentifierValueType:
+		return 5
+	default:
+		return 6 + int(t)
+	}
+}
+
+// SortValues sorts values in place in the order defined by Compare. Equal
+// values keep their original order.
+func SortValues(vs []Value) {
+	slices.SortStableFunc(vs, Compare)
+}
+
+// SortValuesFunc sorts values in place using less. Equal values keep their
+// original order.
+func SortValuesFunc(vs []Value, less func(a, b Value) bool) {
+	slices.SortStableFunc(vs, func(a, b Value) int {
+		switch {
+		case less(a, b):
+			return -1
+		case less(b, a):
+			return +1
+		default:
+			return 0
+		}
+	})
+}
+
 // IsNil checks if a Value is a zero value.
 func IsNil(v Value) bool {
 	if check, ok := As[interface{ IsNil() bool }](v); ok {
@@ -221,6 +674,27 @@ func IsNil(v Value) bool {
 	return false
 }
 
+// Source returns the text of a scalar Value as written in the input.
+//
+// Unlike Raw it keeps the original quotes of strings, e.g. 'a' instead of
+// "a". Lists and maps are not verbatim: they are rebuilt from the source of
+// their items joined by the canonical separators, e.g. 'k': 'v' becomes
+// 'k':'v'.
+func Source(v Value) string {
+	if src, ok := As[interface{ Source() string }](v); ok {
+		return src.Source()
+	}
+	return v.Raw()
+}
+
+// IsZero checks if a Value is absent or a zero value.
+//
+// Besides nil this covers false, numbers equal to zero as well as empty
+// strings, lists and maps.
+func IsZero(v Value) bool {
+	return v == nil || IsNil(v)
+}
+
 // ToString attempts to convert a Value to a string value.
 func ToString(v Value) (string, error) {
 	if conv, ok := As[interface{ ToString() (string, error) }](v); ok {
@@ -230,6 +704,37 @@ func ToString(v Value) (string, error) {
 	return "", fmt.Errorf("value of type %s is not string-convertible", v.Type())
 }
 
+// textOf returns the decoded text of a string-convertible Value, or its raw
+// text otherwise.
+func textOf(v Value) string {
+	if s, err := ToString(v); err == nil {
+		return s
+	}
+	return v.Raw()
+}
+
+// keyString returns the text of a Value used as a key in a string-keyed map.
+//
+// Numeric keys are normalized to their canonical decimal form, e.g. 0xFF
+// becomes 255, all other keys are returned as by textOf.
+func keyString(v Value) string {
+	n, ok := As[NumberValue](v)
+	if !ok {
+		return textOf(v)
+	}
+
+	if i, err := n.ToInt(); err == nil {
+		return strconv.FormatInt(i, 10)
+	}
+	if u, err := n.ToUint(); err == nil {
+		return strconv.FormatUint(u, 10)
+	}
+	if f, err := n.ToFloat(); err == nil {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return n.Raw()
+}
+
 // ToFloat attempts to convert a Value to a float value.
 func ToFloat(v Value) (float64, error) {
 	if conv, ok := As[interface{ ToFloat() (float64, error) }](v); ok {
@@ -263,3 +768,181 @@ func ToBool(v Value) (bool, error) {
 	}
 	return false, fmt.Errorf("value of type %s is not bool-convertible", v.Type())
 }
+
+// CanString reports whether ToString would succeed for the Value. Strings and
+// identifiers without escape sequences are checked by their text alone.
+func CanString(v Value) bool {
+	if str, ok := As[StringValue](v); ok && len(str.raw) >= 2 && str.raw[0] == '"' && str.raw[len(str.raw)-1] == '"' {
+		// The cases strconv.Unquote accepts without decoding.
+		if text := str.raw[1 : len(str.raw)-1]; !strings.ContainsAny(text, "\\\"\n") {
+			return utf8.ValidString(text)
+		}
+	}
+	if id, ok := As[IdentifierValue](v); ok && !strings.ContainsRune(id.raw, '\\') {
+		return true
+	}
+
+	conv, ok := As[interface{ ToString() (string, error) }](v)
+	if !ok {
+		return false
+	}
+	_, err := conv.ToString()
+	return err == nil
+}
+
+// CanFloat reports whether ToFloat would succeed for the Value. Integers
+// within the range of uint64 are checked by their text alone.
+func CanFloat(v Value) bool {
+	if n, ok := As[NumberValue](v); ok {
+		if fits, _ := n.integerFits(uintDigits, true); fits {
+			return true
+		}
+	}
+
+	conv, ok := As[interface{ ToFloat() (float64, error) }](v)
+	if !ok {
+		return false
+	}
+	_, err := conv.ToFloat()
+	return err == nil
+}
+
+// CanInt reports whether ToInt would succeed for the Value. Numbers are
+// checked by their text alone unless it is too long to tell.
+func CanInt(v Value) bool {
+	if n, ok := As[NumberValue](v); ok {
+		if fits, known := n.integerFits(intDigits, true); known {
+			return fits
+		}
+	}
+
+	conv, ok := As[interface{ ToInt() (int64, error) }](v)
+	if !ok {
+		return false
+	}
+	_, err := conv.ToInt()
+	return err == nil
+}
+
+// CanUint reports whether ToUint would succeed for the Value. Numbers are
+// checked by their text alone unless it is too long to tell.
+func CanUint(v Value) bool {
+	if n, ok := As[NumberValue](v); ok {
+		if fits, known := n.integerFits(uintDigits, false); known {
+			return fits
+		}
+	}
+
+	conv, ok := As[interface{ ToUint() (uint64, error) }](v)
+	if !ok {
+		return false
+	}
+	_, err := conv.ToUint()
+	return err == nil
+}
+
+// CanBool reports whether ToBool would succeed for the Value.
+func CanBool(v Value) bool {
+	if _, ok := As[BooleanValue](v); ok {
+		return true
+	}
+
+	conv, ok := As[interface{ ToBool() (bool, error) }](v)
+	if !ok {
+		return false
+	}
+	_, err := conv.ToBool()
+	return err == nil
+}
+
+// intDigits and uintDigits hold the number of digits up to which integers of
+// each base always fit into an int64 and an uint64.
+var (
+	intDigits  = map[int]int{2: 63, 8: 20, 10: 18, 16: 15}
+	uintDigits = map[int]int{2: 64, 8: 21, 10: 19, 16: 16}
+)
+
+// integerFits reports whether the number is an integer of at most maxDigits
+// digits for its base, and whether this is known from its text alone. Texts
+// with underscores, leading zeros or more digits need a conversion to tell.
+// A sign is rejected unless signed is set, as by strconv.ParseUint.
+func (v NumberValue) integerFits(maxDigits map[int]int, signed bool) (fits, known bool) {
+	s := v.raw
+	if len(s) > 0 && isNumericSign(rune(s[0])) {
+		if !signed {
+			return false, true
+		}
+		s = s[1:]
+	}
+	if strings.ContainsRune(s, '_') {
+		return false, false
+	}
+
+	base := 10
+	if len(s) > 2 && s[0] == '0' {
+		switch s[1] {
+		case 'x', 'X':
+			base, s = 16, s[2:]
+		case 'o', 'O':
+			base, s = 8, s[2:]
+		case 'b', 'B':
+			base, s = 2, s[2:]
+		}
+	}
+
+	switch {
+	case base == 10 && strings.ContainsAny(s, ".eE"), base == 16 && strings.ContainsAny(s, ".pP"):
+		// A fraction or an exponent.
+		return false, true
+	case base == 10 && len(s) > 1 && s[0] == '0':
+		// Read as an octal number by strconv.
+		return false, false
+	case len(s) > maxDigits[base]:
+		return false, false
+	}
+	return true, true
+}
+
+// NewNumber returns a NumberValue for s after checking that s is a number in
+// plainfields notation, e.g. 42, -1.5e3 or 0xFF.
+func NewNumber(s string) (NumberValue, error) {
+	raw, err := lexSingle(s, TokenNumber, "number")
+	return NumberValue{raw: raw}, err
+}
+
+// NewIdentifier returns an IdentifierValue for s after checking that s is a
+// valid identifier. Keywords such as true and nil are rejected.
+func NewIdentifier(s string) (IdentifierValue, error) {
+	raw, err := lexSingle(s, TokenIdentifier, "identifier")
+	return IdentifierValue{raw: raw}, err
+}
+
+// NewString returns a StringValue holding s, quoting and escaping it as needed.
+func NewString(s string) StringValue {
+	return StringValue{raw: strconv.Quote(s)}
+}
+
+// NewBoolean returns a BooleanValue holding b.
+func NewBoolean(b bool) BooleanValue {
+	return BooleanValue{raw: strconv.FormatBool(b)}
+}
+
+// lexSingle returns s if it lexes as exactly one token of type typ, name
+// describes the expected token in errors.
+func lexSingle(s string, typ TokenType, name string) (string, error) {
+	var tokens []Token
+	for tok := range Lex(s) {
+		tokens = append(tokens, tok)
+		if len(tokens) > 2 {
+			break
+		}
+	}
+
+	switch {
+	case tokens[0].Typ == TokenError:
+		return "", fmt.Errorf("invalid %s %q: %s", name, s, tokens[0].Val)
+	case len(tokens) != 2 || tokens[0].Typ != typ || tokens[0].Val != s:
+		return "", fmt.Errorf("invalid %s: %q", name, s)
+	}
+	return s, nil
+}
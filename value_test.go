@@ -2,6 +2,7 @@ package kaval
 
 import (
 	"fmt"
+	"slices"
 	"testing"
 )
 
@@ -17,6 +18,11 @@ func TestValueType_GoString(t *testing.T) {
 		{NumberValueType, "NumberValueType", "number"},
 		{IdentifierValueType, "IdentifierValueType", "identifier"},
 		{StringValueType, "StringValueType", "string"},
+		{ListValueType, "ListValueType", "list"},
+		{MapValueType, "MapValueType", "map"},
+		{ReferenceValueType, "ReferenceValueType", "reference"},
+		{ZeroValueType, "ZeroValueType", "zero"},
+		{UndefinedValueType, "UndefinedValueType", "undefined"},
 		{ValueType(999), "ValueType(999)", "ValueType(999)"}, // unknown case
 	}
 
@@ -35,6 +41,40 @@ func TestValueType_GoString(t *testing.T) {
 	}
 }
 
+func TestValueType_Friendly(t *testing.T) {
+	tests := []struct {
+		vt       ValueType
+		expected string
+	}{
+		{InvalidValueType, "invalid value"},
+		{NilValueType, "nil"},
+		{BooleanValueType, "boolean"},
+		{NumberValueType, "number"},
+		{IdentifierValueType, "text"},
+		{StringValueType, "text"},
+		{ListValueType, "list"},
+		{MapValueType, "map"},
+		{ReferenceValueType, "reference"},
+		{ZeroValueType, "empty value"},
+		{UndefinedValueType, "missing value"},
+		{ValueType(999), "ValueType(999)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.vt.GoString(), func(t *testing.T) {
+			if got := tt.vt.Friendly(); got != tt.expected {
+				t.Errorf("Friendly() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	// Conversion errors keep the type names of String.
+	_, err := ToInt(IdentifierValue{"abc"})
+	if want := "value of type identifier is not int-convertible"; err == nil || err.Error() != want {
+		t.Errorf("ToInt() error = %v, want %q", err, want)
+	}
+}
+
 // collectEvent collects a single event from the parser.
 func collectEvent[T any](input string, options ...ParseOptions) (T, error) {
 	var zero T
@@ -88,6 +128,9 @@ func TestValue_Conversions(t *testing.T) {
 		{input: `'hello'`, wantRaw: p(`"hello"`), wantString: p("hello")},
 		{input: `""`, wantString: p(""), wantNil: true},
 		{input: `''`, wantRaw: p(`""`), wantString: p(""), wantNil: true},
+		{input: `"a"`, wantString: p("a")},
+		{input: `"  "`, wantString: p("  ")},
+		{input: `'  '`, wantRaw: p(`"  "`), wantString: p("  ")},
 
 		{input: `-42`, wantInt: p(int64(-42)), wantFloat: p(float64(-42))},
 		{input: `23`, wantInt: p(int64(23)), wantUint: p(uint64(23)), wantFloat: p(float64(23))},
@@ -106,6 +149,14 @@ func TestValue_Conversions(t *testing.T) {
 		{input: "0o644", wantInt: p(int64(420)), wantUint: p(uint64(420)), wantFloat: p(float64(420))},
 		{input: "0b01100101", wantInt: p(int64(101)), wantUint: p(uint64(101)), wantFloat: p(float64(101))},
 
+		{input: "-0xFF", wantInt: p(int64(-255)), wantFloat: p(float64(-255))},
+		{input: "+0xFF", wantInt: p(int64(255)), wantFloat: p(float64(255))},
+		{input: "-0x1.8p1", wantFloat: p(float64(-3.0))},
+		{input: "-0x23.1", wantFloat: p(float64(-35.0625))},
+		{input: "+0b101", wantInt: p(int64(5)), wantFloat: p(float64(5))},
+		{input: "-0b101", wantInt: p(int64(-5)), wantFloat: p(float64(-5))},
+		{input: "-0o17", wantInt: p(int64(-15)), wantFloat: p(float64(-15))},
+
 		{input: `true`, wantBool: p(true)},
 		{input: `false`, wantBool: p(false), wantNil: true},
 	}
@@ -134,6 +185,10 @@ func TestValue_Conversions(t *testing.T) {
 			if got := IsNil(ev); got != tt.wantNil {
 				t.Errorf("IsNil() = %t, want %t", got, tt.wantNil)
 			}
+
+			if got := IsZero(ev.Value); got != tt.wantNil {
+				t.Errorf("IsZero() = %t, want %t", got, tt.wantNil)
+			}
 		})
 	}
 }
@@ -163,6 +218,11 @@ func TestNumberValue(t *testing.T) {
 
 		{inp: "1", isFloat: false, isSigned: false},
 		{inp: "-1", isFloat: false, isSigned: true},
+
+		{inp: "-0xFF", isFloat: false, isSigned: true},
+		{inp: "-0o17", isFloat: false, isSigned: true},
+		{inp: "-0b101", isFloat: false, isSigned: true},
+		{inp: "+0b101", isFloat: false, isSigned: false},
 	}
 	for _, tc := range tt {
 		tc := tc
@@ -220,8 +280,10 @@ func TestValueStringer(t *testing.T) {
 		{"NumberValue unsigned", NumberValue{"42"}, "42 (number)"},
 		{"NumberValue signed", NumberValue{"-7"}, "-7 (number)"},
 		{"NumberValue float", NumberValue{"3.14"}, "3.14 (number)"},
-		{"StringValue quoted", StringValue{`"hello"`}, `"hello" (string)`},
+		{"StringValue quoted", StringValue{raw: `"hello"`}, `"hello" (string)`},
 		{"IdentifierValue", IdentifierValue{"fooBar"}, "fooBar (identifier)"},
+		{"ZeroValue", ZeroValue{}, " (zero)"},
+		{"UndefinedValue", UndefinedValue{}, " (undefined)"},
 	}
 
 	for _, tt := range tests {
@@ -238,3 +300,408 @@ func TestValueStringer(t *testing.T) {
 		})
 	}
 }
+
+func TestValue_CanConvert(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     Value
+		canString bool
+		canInt    bool
+		canUint   bool
+		canFloat  bool
+		canBool   bool
+	}{
+		{"integer", NumberValue{"42"}, false, true, true, true, false},
+		{"negative integer", NumberValue{"-42"}, false, true, false, true, false},
+		{"float", NumberValue{"3.14"}, false, false, false, true, false},
+		{"hex", NumberValue{"0xFF"}, false, true, true, true, false},
+		{"out of int range", NumberValue{"18446744073709551615"}, false, false, true, true, false},
+		{"out of uint range", NumberValue{"123456789012345678901234"}, false, false, false, true, false},
+		{"out of hex range", NumberValue{"0x1_0000_0000_0000_0000"}, false, false, false, false, false},
+		{"plus sign", NumberValue{"+5"}, false, true, false, true, false},
+		{"exponent", NumberValue{"1e3"}, false, false, false, true, false},
+		{"hex digit e", NumberValue{"0xE"}, false, true, true, true, false},
+		{"underscores", NumberValue{"1_000"}, false, true, true, true, false},
+		{"leading zero", NumberValue{"0755"}, false, true, true, true, false},
+		{"leading zero with non-octal digit", NumberValue{"09"}, false, false, false, true, false},
+		{"escaped identifier", IdentifierValue{`a\,b`}, true, false, false, false, false},
+		{"escaped string", StringValue{raw: `"a\nb"`}, true, false, false, false, false},
+		{"identifier", IdentifierValue{"abc"}, true, false, false, false, false},
+		{"string", StringValue{raw: `"abc"`}, true, false, false, false, false},
+		{"malformed string", StringValue{raw: `"abc`}, false, false, false, false, false},
+		{"boolean", BooleanValue{"true"}, false, false, false, false, true},
+		{"nil", NilValue{}, false, false, false, false, false},
+		{"value event", ValueEvent{NumberValue{"7"}}, false, true, true, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanString(tt.value); got != tt.canString {
+				t.Errorf("CanString() = %t, want %t", got, tt.canString)
+			}
+			if got := CanInt(tt.value); got != tt.canInt {
+				t.Errorf("CanInt() = %t, want %t", got, tt.canInt)
+			}
+			if got := CanUint(tt.value); got != tt.canUint {
+				t.Errorf("CanUint() = %t, want %t", got, tt.canUint)
+			}
+			if got := CanFloat(tt.value); got != tt.canFloat {
+				t.Errorf("CanFloat() = %t, want %t", got, tt.canFloat)
+			}
+			if got := CanBool(tt.value); got != tt.canBool {
+				t.Errorf("CanBool() = %t, want %t", got, tt.canBool)
+			}
+
+			// The checks agree with the conversions.
+			_, errString := ToString(tt.value)
+			_, errInt := ToInt(tt.value)
+			_, errUint := ToUint(tt.value)
+			_, errFloat := ToFloat(tt.value)
+			_, errBool := ToBool(tt.value)
+			got := []bool{errString == nil, errInt == nil, errUint == nil, errFloat == nil, errBool == nil}
+			want := []bool{tt.canString, tt.canInt, tt.canUint, tt.canFloat, tt.canBool}
+			if !slices.Equal(got, want) {
+				t.Errorf("conversions succeed = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseHexFloat(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+	}{
+		{"0x1.8p3", 12},
+		{"0x0.1p-4", 0.00390625},
+		{"0x1p1", 2},
+		{"0X1P-2", 0.25},
+		{"0x23.1", 35.0625},
+		{"0x.8", 0.5},
+		{"0x1.", 1},
+		{"0x1.fffffffffffffp1023", 1.7976931348623157e308},
+		{"0x0.123456789abcdef0123p0", 0.07111111111111111},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseHexFloat(tt.input)
+			if err != nil {
+				t.Fatalf("parseHexFloat() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseHexFloat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantRaw string
+		wantSrc string
+	}{
+		{`"a\nb"`, `"a\nb"`, `"a\nb"`},
+		{`'a\nb'`, `"a\nb"`, `'a\nb'`},
+		{`0x1_F`, `0x1_F`, `0x1_F`},
+		{`name`, `name`, `name`},
+		{`nil`, `nil`, `nil`},
+		{`'a';"b";c`, `"a";"b";c`, `'a';"b";c`},
+		{`'k': 'v';n:1`, `"k":"v";n:1`, `'k':'v';n:1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			doc, err := decodeDocument(Parse(tt.input))
+			if err != nil {
+				t.Fatalf("decodeDocument() error = %v", err)
+			}
+			v := doc.ordered.items[0]
+
+			if got := v.Raw(); got != tt.wantRaw {
+				t.Errorf("Raw() = %q, want %q", got, tt.wantRaw)
+			}
+			if got := Source(v); got != tt.wantSrc {
+				t.Errorf("Source() = %q, want %q", got, tt.wantSrc)
+			}
+		})
+	}
+}
+
+func TestReferenceValue_Name(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+	}{
+		{"@HOME", "HOME"},
+		{"$user-dir", "user-dir"},
+		{"§x", "x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			if got := (ReferenceValue{raw: tt.raw}).Name(); got != tt.expected {
+				t.Errorf("Name() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b     Value
+		expected int
+	}{
+		{NumberValue{"1"}, NumberValue{"2"}, -1},
+		{NumberValue{"0x10"}, NumberValue{"9"}, +1},
+		{NumberValue{"1.0"}, NumberValue{"1"}, 0},
+		{NumberValue{"-5"}, NumberValue{"-0b1"}, -1},
+		{NumberValue{"9007199254740993"}, NumberValue{"9007199254740992"}, +1},
+		{NumberValue{"18446744073709551615"}, NumberValue{"18446744073709551614"}, +1},
+		{NumberValue{"-1"}, NumberValue{"18446744073709551615"}, -1},
+		{NumberValue{"1.5"}, NumberValue{"9007199254740993"}, -1},
+		{StringValue{raw: `"b"`}, IdentifierValue{"a"}, +1},
+		{StringValue{raw: `"a"`}, IdentifierValue{"a"}, 0},
+		{BooleanValue{"false"}, BooleanValue{"true"}, -1},
+		{NilValue{}, NilValue{}, 0},
+		{NilValue{}, BooleanValue{"false"}, -1},
+		{ZeroValue{}, ZeroValue{}, 0},
+		{ZeroValue{}, NilValue{}, -1},
+		{UndefinedValue{}, UndefinedValue{}, 0},
+		{UndefinedValue{}, ZeroValue{}, -1},
+		{BooleanValue{"true"}, NumberValue{"0"}, -1},
+		{NumberValue{"100"}, IdentifierValue{"a"}, -1},
+		{ListValue{}, StringValue{raw: `"z"`}, +1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a.Raw()+" "+tt.b.Raw(), func(t *testing.T) {
+			if got := Compare(tt.a, tt.b); got != tt.expected {
+				t.Errorf("Compare(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.expected)
+			}
+			if got := Compare(tt.b, tt.a); got != -tt.expected {
+				t.Errorf("Compare(%v, %v) = %d, want %d", tt.b, tt.a, got, -tt.expected)
+			}
+		})
+	}
+}
+
+func TestSortValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"numbers", "3;1;2", "1;2;3"},
+		{"mixed bases", "0x10;-1;0b11;2.5", "-1;2.5;0b11;0x10"},
+		{"mixed kinds", `b;2;"a";nil;true;1`, `nil;true;1;2;"a";b`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := decodeDocument(Parse(tt.input))
+			if err != nil {
+				t.Fatalf("decodeDocument() error = %v", err)
+			}
+			list := doc.ordered.items[0].(ListValue)
+
+			SortValues(list.items)
+			if got := list.Raw(); got != tt.expected {
+				t.Errorf("SortValues() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSortValuesFunc(t *testing.T) {
+	vs := []Value{IdentifierValue{"bb"}, IdentifierValue{"a"}, IdentifierValue{"ccc"}, IdentifierValue{"dd"}}
+
+	// Sort by length, keeping the order of equal lengths.
+	SortValuesFunc(vs, func(a, b Value) bool { return len(a.Raw()) < len(b.Raw()) })
+
+	got := ListValue{vs}.Raw()
+	if want := "a;bb;dd;ccc"; got != want {
+		t.Errorf("SortValuesFunc() = %q, want %q", got, want)
+	}
+}
+
+func TestIdentifierValue_ToString(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+		wantErr  bool
+	}{
+		{`plain`, "plain", false},
+		{`with-dash_1`, "with-dash_1", false},
+		{`a\,b`, "a,b", false},
+		{`a\;b\:c\=d`, "a;b:c=d", false},
+		{`a\ b`, "a b", false},
+		{`a\\b`, `a\b`, false},
+		{`line\nbreak`, "line\nbreak", false},
+		{`tab\té`, "tab\té", false},
+		{`caf\é`, "café", false},
+		{`trailing\`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := IdentifierValue{tt.raw}.ToString()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToString() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ToString() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStringValue_ToString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{`"\u00e9"`, "é", false},
+		{`'\u00e9'`, "é", false},
+		{`"\x41"`, "A", false},
+		{`'\x41'`, "A", false},
+		{`'\t\\'`, "\t\\", false},
+		{`'it\'s'`, "it's", false},
+		{`"it\'s"`, "it's", false},
+		{`'say "hi"'`, `say "hi"`, false},
+		{`'say \"hi\"'`, `say "hi"`, false},
+		{`"\q"`, "", true},
+		{`'\q'`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			m, err := ToMap("s=" + tt.input)
+			if err != nil {
+				t.Fatalf("ToMap() error = %v", err)
+			}
+
+			got, err := ToString(m["s"])
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToString() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ToString() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestListValue_Join(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{`tags=a;b;c`, "a, b, c", false},
+		{`tags=dev;"prod env"`, "dev, prod env", false},
+		{`tags=a;1`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			m, err := ToMap(tt.input)
+			if err != nil {
+				t.Fatalf("ToMap() error = %v", err)
+			}
+			list, ok := As[ListValue](m["tags"])
+			if !ok {
+				t.Fatalf("tags = %v, want a list", m["tags"])
+			}
+
+			got, err := list.Join(", ")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Join() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("Join() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewNumber(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{"42", false},
+		{"-1.5e3", false},
+		{"0xFF", false},
+		{"1_000", false},
+		{"", true},
+		{"notanumber", true},
+		{"1e", true},
+		{" 1", true},
+		{"1,2", true},
+		{"--1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := NewNumber(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewNumber() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Raw() != tt.input {
+				t.Errorf("NewNumber() = %q, want %q", got.Raw(), tt.input)
+			}
+		})
+	}
+}
+
+func TestNewIdentifier(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{"name", false},
+		{"with-dash_1", false},
+		{"", true},
+		{"true", true},
+		{"nil", true},
+		{"a b", true},
+		{"a=b", true},
+		{"1abc", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := NewIdentifier(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewIdentifier() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Raw() != tt.input {
+				t.Errorf("NewIdentifier() = %q, want %q", got.Raw(), tt.input)
+			}
+		})
+	}
+}
+
+func TestNewString(t *testing.T) {
+	for _, s := range []string{"", "plain", `say "hi"`, "line\nbreak"} {
+		got, err := NewString(s).ToString()
+		if err != nil {
+			t.Fatalf("NewString(%q).ToString() error = %v", s, err)
+		}
+		if got != s {
+			t.Errorf("NewString(%q).ToString() = %q", s, got)
+		}
+	}
+}
+
+func TestNewBoolean(t *testing.T) {
+	if got := NewBoolean(true); got.Raw() != "true" {
+		t.Errorf("NewBoolean(true) = %q, want %q", got.Raw(), "true")
+	}
+	if got := NewBoolean(false); got.Raw() != "false" {
+		t.Errorf("NewBoolean(false) = %q, want %q", got.Raw(), "false")
+	}
+}